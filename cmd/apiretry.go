@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/aws/ratelimit"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+)
+
+const (
+	retryModeStandard = "standard"
+	retryModeAdaptive = "adaptive"
+)
+
+// defaultMaxAPIRetries is a little more than the SDK default of 3, as many
+// runs in parallel easily exceed the request rate of DescribeTasks and
+// GetLogEvents.
+const defaultMaxAPIRetries = 5
+
+var maxAPIRetries = defaultMaxAPIRetries
+var apiRetryMode = retryModeStandard
+
+// initAPIRetries exits if --max-api-retries or --api-retry-mode is invalid.
+func initAPIRetries() {
+	if maxAPIRetries < 0 {
+		fmt.Println("--max-api-retries must not be negative")
+		os.Exit(1)
+	}
+	if apiRetryMode != retryModeStandard && apiRetryMode != retryModeAdaptive {
+		fmt.Printf("Invalid --api-retry-mode %q, expected %s or %s\n", apiRetryMode, retryModeStandard, retryModeAdaptive)
+		os.Exit(1)
+	}
+}
+
+// apiRetryer returns the retryer of all clients: failed requests are
+// retried up to --max-api-retries times with exponential backoff. Requests
+// rejected because the credentials expired are retried once credentials
+// are invalidated, so they are renewed.
+func apiRetryer(credentials *aws.CredentialsCache) func() aws.Retryer {
+	return func() aws.Retryer {
+		return retry.NewStandard(func(o *retry.StandardOptions) {
+			o.MaxAttempts = maxAPIRetries + 1
+			// Long waits retry far more often than the SDK's retry quota
+			// allows.
+			o.RateLimiter = ratelimit.None
+			o.Retryables = append(o.Retryables, retryExpired(credentials))
+		})
+	}
+}
+
+// retryExpired makes errors of expired credentials retryable after
+// invalidating credentials.
+func retryExpired(credentials *aws.CredentialsCache) retry.IsErrorRetryable {
+	return retry.IsErrorRetryableFunc(func(err error) aws.Ternary {
+		var apiErr smithy.APIError
+		if !errors.As(err, &apiErr) {
+			return aws.UnknownTernary
+		}
+		switch apiErr.ErrorCode() {
+		case "ExpiredToken", "ExpiredTokenException", "RequestExpired":
+			credentials.Invalidate()
+			return aws.TrueTernary
+		}
+		return aws.UnknownTernary
+	})
+}
+
+// limitRequests makes the clients of cfg wait for the rate limiter of
+// their service before every attempt of a request in adaptive mode.
+func limitRequests(cfg *aws.Config) {
+	if apiRetryMode != retryModeAdaptive {
+		return
+	}
+	cfg.APIOptions = append(cfg.APIOptions, func(stack *middleware.Stack) error {
+		return stack.Finalize.Insert(middleware.FinalizeMiddlewareFunc("LimitRequests", limitAttempt), "Retry", middleware.After)
+	})
+}
+
+func limitAttempt(ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler) (middleware.FinalizeOutput, middleware.Metadata, error) {
+	limiter := limiterFor(awsmiddleware.GetServiceID(ctx))
+	limiter.wait(ctx)
+	out, metadata, err := next.HandleFinalize(ctx, in)
+	if err == nil {
+		limiter.succeeded()
+	} else if retry.IsErrorThrottles(retry.DefaultThrottles).IsErrorThrottle(err) == aws.TrueTernary {
+		limiter.throttled()
+	}
+	return out, metadata, err
+}
+
+var rateLimiters = map[string]*rateLimiter{}
+var rateLimitersMu sync.Mutex
+
+// limiterFor returns the rate limiter shared by all clients of service.
+func limiterFor(service string) *rateLimiter {
+	rateLimitersMu.Lock()
+	defer rateLimitersMu.Unlock()
+	limiter, ok := rateLimiters[service]
+	if !ok {
+		limiter = &rateLimiter{service: service}
+		rateLimiters[service] = limiter
+	}
+	return limiter
+}
+
+const (
+	// minRequestRate and maxRequestRate bound the rate of a throttled
+	// service in requests per second. Above maxRequestRate the limit is
+	// lifted again.
+	minRequestRate = 0.5
+	maxRequestRate = 50.0
+)
+
+// rateLimiter spaces out the requests to a service once it throttled them.
+// A throttled request cuts the rate to 70% of what was sent in the last
+// second, and every successful request raises it so that it grows by about
+// one request per second each second, until the limit is lifted.
+type rateLimiter struct {
+	mu      sync.Mutex
+	service string
+	// rate is the allowed requests per second, 0 while not limited.
+	rate float64
+	next time.Time
+	// windowStart and windowCount measure the rate requests are sent at.
+	windowStart time.Time
+	windowCount int
+	sentRate    float64
+}
+
+// wait blocks until the next request may be sent or ctx is done.
+func (l *rateLimiter) wait(ctx context.Context) {
+	l.mu.Lock()
+	now := time.Now()
+	if elapsed := now.Sub(l.windowStart); elapsed >= time.Second {
+		l.sentRate = float64(l.windowCount) / elapsed.Seconds()
+		l.windowStart, l.windowCount = now, 0
+	}
+	l.windowCount++
+	if l.rate == 0 {
+		l.mu.Unlock()
+		return
+	}
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(time.Duration(float64(time.Second) / l.rate))
+	l.mu.Unlock()
+	if delay <= 0 {
+		return
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(delay):
+	}
+}
+
+func (l *rateLimiter) throttled() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	rate := l.rate
+	if rate == 0 {
+		rate = l.sentRate
+	}
+	rate *= 0.7
+	if rate < minRequestRate {
+		rate = minRequestRate
+	}
+	if rate != l.rate {
+		debugf("%s throttled requests, limiting them to %.1f per second", l.service, rate)
+	}
+	l.rate = rate
+}
+
+func (l *rateLimiter) succeeded() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.rate == 0 {
+		return
+	}
+	l.rate += 1 / l.rate
+	if l.rate > maxRequestRate {
+		debugf("lifting the request limit of %s", l.service)
+		l.rate = 0
+	}
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	logstypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+var archiveLogsTo string
+
+// archiveLogs uploads the complete logs of the run of state and output as
+// its metadata below the S3 prefix of state, in a directory named after the
+// first task of the run. The logs are spooled to a temporary file, so
+// memory use does not grow with their size.
+func archiveLogs(ctx context.Context, cfg aws.Config, state *runState, output *runResult) error {
+	bucket, prefix, err := parseS3URI(state.ArchiveLogs)
+	if err != nil {
+		return err
+	}
+	prefix = joinS3Key(prefix, taskID(state.TaskArn))
+	spool, err := ioutil.TempFile("", "ecs-run-task-logs")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+	svc := cloudwatchlogs.NewFromConfig(cfg)
+	for _, s := range state.LogStreams {
+		if err := writeArchivedStream(ctx, svc, s, spool); err != nil {
+			return err
+		}
+	}
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	metadata, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Archiving logs to s3://%s/%s/...\n", bucket, prefix)
+	uploader := manager.NewUploader(newS3Client(cfg))
+	_, err = uploader.Upload(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(joinS3Key(prefix, "logs.txt")),
+		Body:        spool,
+		ContentType: aws.String("text/plain; charset=utf-8"),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = uploader.Upload(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(joinS3Key(prefix, "metadata.json")),
+		Body:        bytes.NewReader(metadata),
+		ContentType: aws.String("application/json"),
+	})
+	return err
+}
+
+// writeArchivedStream writes every event of a log stream to w, each line
+// with its timestamp in RFC 3339 and the label of the stream. Masked values
+// stay masked.
+func writeArchivedStream(ctx context.Context, svc *cloudwatchlogs.Client, s logStream, w io.Writer) error {
+	var assembler eventAssembler
+	write := func(event logstypes.OutputLogEvent) {
+		timestamp := eventTime(event).UTC().Format("2006-01-02T15:04:05.000Z07:00")
+		message := maskSecrets(aws.ToString(event.Message))
+		if s.Label != "" {
+			fmt.Fprintf(w, "[%s] %s | %s\n", timestamp, s.Label, message)
+			return
+		}
+		fmt.Fprintf(w, "[%s] %s\n", timestamp, message)
+	}
+	input := &cloudwatchlogs.GetLogEventsInput{
+		LogGroupName:  aws.String(s.Group),
+		LogStreamName: aws.String(s.Stream),
+		StartFromHead: aws.Bool(true),
+	}
+	for {
+		resp, err := svc.GetLogEvents(ctx, input)
+		if err != nil {
+			return err
+		}
+		sortEvents(resp.Events)
+		for _, event := range resp.Events {
+			for _, complete := range assembler.add(event) {
+				write(complete)
+			}
+		}
+		// The forward token stays the same once the end of the stream is reached.
+		if resp.NextForwardToken == nil || aws.ToString(resp.NextForwardToken) == aws.ToString(input.NextToken) {
+			break
+		}
+		input.NextToken = resp.NextForwardToken
+	}
+	for _, event := range assembler.flush() {
+		write(event)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/spf13/cobra"
+)
+
+// attachCmd picks up monitoring of a task launched earlier
+var attachCmd = &cobra.Command{
+	Use:   "attach <task-arn>",
+	Short: "Wait for a running task, print its logs and exit with its exit code",
+	Long: `Wait for a task that is already running, print its logs and exit with
+its exit code, as if it had just been launched. Use it to monitor a task
+launched with --no-wait, or from a CI retry or a second terminal.
+
+The task can be given as ARN, or as ID together with --cluster.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		validateLogFlags()
+		validateLogPatternFlags()
+		openLogFile()
+		ctx := cmd.Context()
+		cfg, cluster, region, task, err := findTask(ctx, args[0])
+		if err != nil {
+			exitWithError("Got error describing task:", err)
+		}
+		taskDefinitionArn := aws.ToString(task.TaskDefinitionArn)
+		td := describeTaskDefinition(ctx, cfg, taskDefinitionArn)
+		validateExitCodeFrom(td)
+		// A run that was launched from this machine keeps the settings and
+		// the lock it was launched with, the flags only fill in what it lacks.
+		state, err := loadState(aws.ToString(task.TaskArn))
+		loaded := err == nil
+		if !loaded {
+			state = &runState{TaskArn: aws.ToString(task.TaskArn)}
+		}
+		state.Owner = lockOwner()
+		if state.Region == "" {
+			state.Region = region
+		}
+		if state.Cluster == "" {
+			state.Cluster = cluster
+		}
+		if state.TaskDefinition == "" {
+			state.TaskDefinition = taskDefinitionArn
+		}
+		if len(state.LogStreams) == 0 {
+			state.LogStreams = taskLogStreams(td, aws.ToString(task.TaskArn))
+		}
+		if state.StartedAt.IsZero() {
+			state.StartedAt = aws.ToTime(task.CreatedAt)
+		}
+		// A saved 0 means no limit, so only the flag given now overrides it.
+		if !loaded || cmd.Flags().Changed("wait-timeout") {
+			state.WaitTimeout = waitTimeout
+		}
+		state.UsageSummary = state.UsageSummary || usageSummary
+		state.Follow = state.Follow || followLogs
+		state.Timeline = state.Timeline || showTimeline
+		if state.ExitCodeFrom == "" {
+			state.ExitCodeFrom = exitCodeFrom
+		}
+		if state.FailOnLogPattern == "" {
+			state.FailOnLogPattern, state.StopOnLogPattern = failOnLogPattern, stopOnLogPattern
+		}
+		if state.LogQuery == "" {
+			state.LogQuery = runLogQuery()
+		}
+		fmt.Printf("Attached to task %s (%s) in an ECS Cluster %s, it is %s\n", taskID(state.TaskArn), taskFamily(taskDefinitionArn), cluster, aws.ToString(task.LastStatus))
+		if err := saveState(state); err != nil {
+			printError("Got error saving state:", err)
+		}
+		if state.LockTable != "" {
+			releaseLockOnExit(ctx, state.lockConfig(ctx, cfg), state.LockTable, state.LockKey, state.LockOwner)
+		}
+		auditOnExit(state)
+		monitorTask(ctx, cfg, state)
+	},
+}
+
+// findTask describes the task given as ARN, or as ID in the cluster given
+// with --cluster. It returns the configuration in the region of the task
+// and the cluster of the task, which is "" if it is not known.
+func findTask(ctx context.Context, task string) (aws.Config, string, string, *ecstypes.Task, error) {
+	cluster, region := ecsCluster, ""
+	if parsed, err := arn.Parse(task); err == nil {
+		region = parsed.Region
+		// Long task ARNs end in task/cluster/id.
+		if parts := strings.Split(parsed.Resource, "/"); len(parts) == 3 && cluster == "" {
+			cluster = parts[1]
+		}
+	}
+	if cluster == "" {
+		return aws.Config{}, "", "", nil, fmt.Errorf("pass --cluster to find task %s", task)
+	}
+	cfg := newRegionConfig(ctx, region)
+	tasks, err := describeTasks(ctx, ecs.NewFromConfig(cfg), cluster, []string{task})
+	if err != nil {
+		return cfg, cluster, region, nil, err
+	}
+	return cfg, cluster, region, &tasks[0], nil
+}
+
+func init() {
+	rootCmd.AddCommand(attachCmd)
+	flags := attachCmd.Flags()
+	flags.StringVarP(&ecsCluster, "cluster", "c", "", "Name of the Cluster, if the task is given by ID")
+	flags.BoolVarP(&followLogs, "follow", "", false, "Print logs while the task is running instead of after it stopped")
+	flags.BoolVarP(&useLiveTail, "live-tail", "", false, "With --follow, have CloudWatch Logs Live Tail push new log lines instead of polling for them")
+	flags.StringVarP(&failOnLogPattern, "fail-on-log-pattern", "", "", "Fail the run if a log line matches this regular expression, even if the task exits with 0")
+	flags.BoolVarP(&stopOnLogPattern, "stop-on-log-pattern", "", false, "Stop the task as soon as a log line matches --fail-on-log-pattern, needs --follow")
+	flags.IntVarP(&maxLogEvents, "max-log-events", "", 0, "Print at most this many log events per stream, 0 for all")
+	flags.StringVarP(&logTime, "log-time", "", logTimeEvent, "Time to print and order log lines by: event (set by the container) or ingestion (set by CloudWatch)")
+	addTimestampsFlag(flags)
+	flags.DurationVarP(&logStreamWait, "log-stream-wait", "", 30*time.Second, "How long to wait for a log stream that does not exist yet before giving up on its logs")
+	flags.DurationVarP(&logsSince, "since", "", 0, "Only print log events of this long ago or later, e.g. 10m")
+	flags.IntVarP(&logTail, "tail", "", 0, "Only print the last this many log events of each stream, 0 for all")
+	flags.StringVarP(&logGrep, "grep", "", "", "Only print log lines matching this regular expression")
+	flags.StringVarP(&logGrepV, "grep-v", "", "", "Do not print log lines matching this regular expression")
+	flags.StringVarP(&logFile, "log-file", "", "", "Also write the printed log lines to this file")
+	flags.BoolVarP(&logFilePrefixes, "log-file-prefixes", "", false, "Write log lines to --log-file with their timestamp and container prefix")
+	flags.StringArrayVarP(&maskFlags, "mask", "", nil, "Replace this value, or regular expression given as /regex/, with *** in printed logs (repeatable)")
+	flags.StringVarP(&logFilterPattern, "filter-pattern", "", "", "Only fetch log events matching this CloudWatch Logs filter pattern")
+	flags.BoolVarP(&filterLogs, "filter-logs", "", false, "Fetch log events with FilterLogEvents from every log stream whose name starts with the name of a container's stream")
+	flags.VarP(waitTimeoutValue{&waitTimeout}, "wait-timeout", "", "How long to wait for the task to stop, or unlimited")
+	flags.BoolVarP(&stopOnInterrupt, "stop-on-interrupt", "", false, "Stop the task when interrupted with Ctrl-C or SIGTERM instead of asking, or leaving it running without a terminal")
+	flags.StringVarP(&exitCodeFrom, "exit-code-from", "", exitCodeFromEssential, "Exit with the code of: essential (the first failed essential container), max (the highest of all containers) or a container name")
+	flags.BoolVarP(&showTimeline, "timeline", "", false, "Print status changes of the task while waiting and when it reached each stage once it stopped")
+	flags.BoolVarP(&logSummary, "log-summary", "", false, "Print how many log lines mention errors and warnings, counted with CloudWatch Logs Insights, after the task stopped")
+	flags.StringVarP(&logQuery, "log-query", "", "", "Print the results of this CloudWatch Logs Insights query over the logs of the task after it stopped, instead of the --log-summary counts")
+	flags.BoolVarP(&usageSummary, "usage-summary", "", false, "Print CPU and memory utilization of the task from Container Insights after it stopped")
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var auditLog string
+
+// auditOutcome is the audit record of the run completeRun finished, nil
+// while it has not.
+var auditOutcome *auditRecord
+
+// auditRecord describes a finished run for the audit log.
+type auditRecord struct {
+	Time           time.Time    `json:"time"`
+	User           string       `json:"user"`
+	Host           string       `json:"host"`
+	Version        string       `json:"version"`
+	Cluster        string       `json:"cluster"`
+	TaskDefinition string       `json:"taskDefinition"`
+	TaskArn        string       `json:"taskArn"`
+	ExitCode       int64        `json:"exitCode"`
+	ExitReason     string       `json:"exitReason"`
+	StopCategory   stopCategory `json:"stopCategory"`
+	Duration       string       `json:"duration"`
+}
+
+// auditOnExit appends an audit record of the run of state to its audit log
+// when the process exits. Runs that fail to launch, time out waiting or fail
+// otherwise before completeRun are recorded with the error they exited with.
+func auditOnExit(state *runState) {
+	if state.AuditLog == "" {
+		return
+	}
+	onExit(func() {
+		record := auditOutcome
+		if record == nil {
+			reason := exitMessage
+			if reason == "" {
+				reason = fmt.Sprintf("ecs-run-task exited with code %d", exitStatus)
+			}
+			record = &auditRecord{ExitCode: int64(exitStatus), ExitReason: reason}
+		}
+		record.Time, record.Cluster, record.TaskDefinition = state.StartedAt, state.Cluster, state.TaskDefinition
+		if state.TaskArn != "" {
+			var taskIDs []string
+			for _, taskArn := range state.tasks() {
+				taskIDs = append(taskIDs, taskID(taskArn))
+			}
+			record.TaskArn = strings.Join(taskIDs, ",")
+		}
+		record.Duration = time.Since(state.StartedAt).Round(time.Second).String()
+		if err := writeAuditRecord(state.AuditLog, *record); err != nil {
+			printError("Got error writing audit record:", err)
+		}
+	})
+}
+
+// writeAuditRecord appends record as a JSON line to path.
+func writeAuditRecord(path string, record auditRecord) error {
+	record.User = currentUser()
+	record.Host, _ = os.Hostname()
+	record.Version = version
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// defaultAuditLog returns ~/.ecs-run-task/audit.log.
+func defaultAuditLog() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".ecs-run-task", "audit.log")
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+var trackDuration bool
+var durationHistory string
+var slowdownThreshold int
+var failOnSlowdown bool
+
+// durationHistorySize is how many recent durations per cluster and task
+// family make up the baseline.
+const durationHistorySize = 10
+
+// durationBaseline is the median of durations, 0 without any.
+func durationBaseline(durations []time.Duration) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[len(sorted)/2]
+}
+
+// readDurationHistory reads the recent durations per cluster and task
+// family from path. A missing file is an empty history.
+func readDurationHistory(path string) (map[string][]time.Duration, error) {
+	history := map[string][]time.Duration{}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return history, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return history, nil
+}
+
+func writeDurationHistory(path string, history map[string][]time.Duration) error {
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+// checkDuration compares the duration of a successful run against the
+// baseline of earlier runs with the same key and records it. It returns
+// whether the run was more than threshold percent slower than the baseline.
+func checkDuration(path string, key string, duration time.Duration, threshold int) (bool, error) {
+	history, err := readDurationHistory(path)
+	if err != nil {
+		return false, err
+	}
+	slow := false
+	if baseline := durationBaseline(history[key]); baseline > 0 {
+		limit := baseline + baseline*time.Duration(threshold)/100
+		if duration > limit {
+			slow = true
+			warnf("run took %s, %d%% longer than the baseline of %s",
+				duration.Round(time.Second), int64(100*(duration-baseline)/baseline), baseline.Round(time.Second))
+		} else {
+			infof("Duration %s, baseline %s", duration.Round(time.Second), baseline.Round(time.Second))
+		}
+	}
+	durations := append(history[key], duration)
+	if len(durations) > durationHistorySize {
+		durations = durations[len(durations)-durationHistorySize:]
+	}
+	history[key] = durations
+	return slow, writeDurationHistory(path, history)
+}
+
+// defaultDurationHistory returns ~/.ecs-run-task/durations.json.
+func defaultDurationHistory() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".ecs-run-task", "durations.json")
+}
@@ -0,0 +1,211 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/batch"
+	batchtypes "github.com/aws/aws-sdk-go-v2/service/batch/types"
+)
+
+const (
+	backendECS   = "ecs"
+	backendBatch = "batch"
+)
+
+// batchLogGroup is where AWS Batch jobs log unless their job definition
+// configures another awslogs group.
+const batchLogGroup = "/aws/batch/job"
+
+var backend string
+var jobQueue string
+var jobDefinition string
+
+// containerCommandPattern matches a --command in the container=command form.
+var containerCommandPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+=`)
+
+// validateBatchFlags exits if flags that AWS Batch has no equivalent for are
+// used with --backend batch, rather than ignoring them.
+func validateBatchFlags() {
+	if backend != backendBatch {
+		return
+	}
+	if len(commandOverrides) > 1 {
+		fmt.Println("--command can only be given once with --backend batch")
+		exit(1)
+	}
+	for _, spec := range commandOverrides {
+		if containerCommandPattern.MatchString(spec) {
+			fmt.Printf("Invalid --command %q, --backend batch jobs have a single container\n", spec)
+			exit(1)
+		}
+	}
+	if followLogs {
+		fmt.Println("--follow cannot be used with --backend batch")
+		exit(1)
+	}
+}
+
+// RunBatchJob submits jobDefinition to jobQueue and waits for the job to
+// finish. It returns the log group and stream of the job, its ID, exit code
+// and status reason.
+func RunBatchJob(ctx context.Context, cfg aws.Config, jobQueue string, jobDefinition string) (string, string, string, int64, string) {
+	svc := batch.NewFromConfig(cfg)
+	input := &batch.SubmitJobInput{
+		JobName:       aws.String(batchJobName(jobDefinition)),
+		JobQueue:      aws.String(jobQueue),
+		JobDefinition: aws.String(jobDefinition),
+	}
+	if len(envOverrides) > 0 {
+		input.ContainerOverrides = &batchtypes.ContainerOverrides{}
+		for _, kv := range envOverrides {
+			input.ContainerOverrides.Environment = append(input.ContainerOverrides.Environment, batchtypes.KeyValuePair{
+				Name:  kv.Name,
+				Value: kv.Value,
+			})
+		}
+	}
+	if len(commandOverrides) > 0 {
+		command, err := splitCommand(commandOverrides[0])
+		if err != nil {
+			exitWithError("Got error parsing --command:", err)
+		}
+		if input.ContainerOverrides == nil {
+			input.ContainerOverrides = &batchtypes.ContainerOverrides{}
+		}
+		input.ContainerOverrides.Command = command
+	}
+	output, err := svc.SubmitJob(ctx, input)
+	if err != nil {
+		exitWithError("Got error submitting job:", err)
+	}
+	jobID := *output.JobId
+	fmt.Println("Submitted job", jobID)
+
+	stopHandlingInterrupts := handleBatchInterrupts(ctx, svc, jobID)
+	defer stopHandlingInterrupts()
+	started := time.Now()
+	terminated := false
+	var status batchtypes.JobStatus
+	var job batchtypes.JobDetail
+	for attempt := 0; ; attempt++ {
+		jobs, err := svc.DescribeJobs(ctx, &batch.DescribeJobsInput{
+			Jobs: []string{jobID},
+		})
+		if err != nil {
+			exitWithError("Got error describing job:", err)
+		}
+		if len(jobs.Jobs) == 0 {
+			exitWithError("Got error describing job:", fmt.Errorf("job %s not found", jobID))
+		}
+		job = jobs.Jobs[0]
+		if job.Status != status {
+			status = job.Status
+			fmt.Println("Job status:", status)
+		}
+		if status == batchtypes.JobStatusSucceeded || status == batchtypes.JobStatusFailed {
+			break
+		}
+		if maxRunTime > 0 && !terminated && time.Since(started) > maxRunTime {
+			fmt.Printf("Job exceeded the maximum run time of %s, terminating it...\n", maxRunTime)
+			_, err := svc.TerminateJob(ctx, &batch.TerminateJobInput{
+				JobId:  aws.String(jobID),
+				Reason: aws.String(fmt.Sprintf("%s of %s", timeoutStopReason, maxRunTime)),
+			})
+			if err != nil {
+				exitWithError("Got error terminating job:", err)
+			}
+			terminated = true
+		}
+		if waitTimeout > 0 && maxRunTime == 0 && time.Since(started) > waitTimeout {
+			exitWithError("Got error waiting for job:", fmt.Errorf("job %s did not finish within --wait-timeout %s, it is still running", jobID, waitTimeout))
+		}
+		delay := pollDelay(attempt)
+		if verbose {
+			fmt.Printf("Check %d: job is %s, next check in %s\n", attempt+1, status, delay.Round(time.Second))
+		}
+		time.Sleep(delay)
+	}
+
+	logGroupName := batchLogGroup
+	var logStreamName string
+	// Multi-node and array jobs have no container, their status tells
+	// whether they succeeded.
+	exitCode := int64(1)
+	if status == batchtypes.JobStatusSucceeded {
+		exitCode = 0
+	}
+	reason := aws.ToString(job.StatusReason)
+	if container := job.Container; container != nil {
+		if container.LogConfiguration != nil {
+			if group, ok := container.LogConfiguration.Options["awslogs-group"]; ok {
+				logGroupName = group
+			}
+		}
+		logStreamName = aws.ToString(container.LogStreamName)
+		if container.ExitCode != nil {
+			exitCode = int64(*container.ExitCode)
+		}
+		if container.Reason != nil {
+			reason = strings.TrimSpace(reason + " " + *container.Reason)
+		}
+	}
+	return logGroupName, logStreamName, jobID, exitCode, reason
+}
+
+// handleBatchInterrupts decides what happens to job jobID when the run gets
+// SIGINT or SIGTERM until the returned function is called, like
+// handleInterrupts does for ECS tasks. A job left running cannot be resumed.
+func handleBatchInterrupts(ctx context.Context, svc *batch.Client, jobID string) func() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-done:
+			return
+		case <-signals:
+		}
+		if !stopOnInterrupt && !confirmStop("job "+jobID) {
+			stopStatusLine()
+			fmt.Println("Leaving the job running")
+			code := int64(exitCodeInterrupted)
+			printRunResult(&runResult{TaskArns: []string{jobID}, ExitCode: &code, Error: "interrupted, the job is left running"})
+			exit(exitCodeInterrupted)
+		}
+		fmt.Println("Terminating the job, interrupt again to exit without waiting...")
+		_, err := svc.TerminateJob(ctx, &batch.TerminateJobInput{
+			JobId:  aws.String(jobID),
+			Reason: aws.String(interruptStopReason),
+		})
+		if err != nil {
+			printError("Got error terminating job:", err)
+		}
+		select {
+		case <-done:
+		case <-signals:
+			exit(exitCodeInterrupted)
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
+
+// batchJobName derives a valid job name from a job definition name or ARN.
+func batchJobName(jobDefinition string) string {
+	return "ecs-run-task-" + strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' {
+			return r
+		}
+		return '-'
+	}, taskFamily(jobDefinition))
+}
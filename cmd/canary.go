@@ -0,0 +1,204 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var canaryBaseline string
+var canaryCandidate string
+var canaryLogPatterns []string
+var canaryMaxSlowdown float64
+
+// canaryCmd runs two revisions of a task definition side by side and
+// compares how they did
+var canaryCmd = &cobra.Command{
+	Use:   "canary",
+	Short: "Run two task definition revisions side by side and compare them",
+	Long: `Run a baseline and a candidate task definition at the same time with the
+same overrides and compare exit codes, durations and how often each
+--log-pattern occurs in their output.
+
+The candidate fails when its exit code differs from the baseline, when it
+takes more than --max-slowdown times as long, or when a log pattern occurs
+more often than in the baseline. The command exits with 0 when the candidate
+passes.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if canaryBaseline == "" || canaryCandidate == "" || ecsCluster == "" {
+			cmd.Usage()
+			os.Exit(1)
+		}
+		if stdinTo != "" || runOutput != "text" {
+			fmt.Println("--stdin-to and --output cannot be used with canary")
+			os.Exit(1)
+		}
+		patterns := make([]*regexp.Regexp, len(canaryLogPatterns))
+		for i, pattern := range canaryLogPatterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				exitWithError("Got error parsing --log-pattern:", err)
+			}
+			patterns[i] = re
+		}
+		confirmProtectedCluster(ecsCluster)
+		if lockTable != "" {
+			lockCanary(cmd.Context())
+		}
+		runCanary(cmd.Flags(), patterns)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(canaryCmd)
+	addRunFlags(canaryCmd.Flags())
+	canaryCmd.Flags().StringVarP(&canaryBaseline, "baseline", "", "", "Task definition known to work, e.g. family:11")
+	canaryCmd.Flags().StringVarP(&canaryCandidate, "candidate", "", "", "Task definition to validate, e.g. family:12")
+	canaryCmd.Flags().StringArrayVarP(&canaryLogPatterns, "log-pattern", "", nil, "Regular expression to count in the output of both runs, the candidate fails if it matches more often (repeatable)")
+	canaryCmd.Flags().Float64VarP(&canaryMaxSlowdown, "max-slowdown", "", 2, "Fail the candidate when it takes more than this many times as long as the baseline, 0 to not compare durations")
+}
+
+// canaryRun is a run of one side of the canary.
+type canaryRun struct {
+	childRun
+	taskDefinition string
+	matches        []int
+}
+
+// runCanary runs the baseline and the candidate with the run flags set in
+// flags, prints a comparison and exits with 0 if the candidate passed.
+func runCanary(flags *pflag.FlagSet, patterns []*regexp.Regexp) {
+	executable, err := os.Executable()
+	if err != nil {
+		exitWithError("Got error finding the ecs-run-task executable:", err)
+	}
+	runs := []*canaryRun{
+		{childRun: childRun{label: "baseline"}, taskDefinition: canaryBaseline},
+		{childRun: childRun{label: "candidate"}, taskDefinition: canaryCandidate},
+	}
+	out := &syncWriter{w: os.Stdout}
+	var wg sync.WaitGroup
+	for _, run := range runs {
+		wg.Add(1)
+		go func(run *canaryRun) {
+			defer wg.Done()
+			run.matches = make([]int, len(patterns))
+			args := append(canaryArgs(flags, run.label), "--task-definition", run.taskDefinition, "--yes")
+			run.childRun = runChild(executable, args, nil, run.label, out, func(line string) {
+				for i, re := range patterns {
+					if re.MatchString(line) {
+						run.matches[i]++
+					}
+				}
+			})
+		}(run)
+	}
+	wg.Wait()
+
+	baseline, candidate := runs[0], runs[1]
+	fmt.Println("Comparison:")
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(table, "\tBASELINE (%s)\tCANDIDATE (%s)\n", baseline.taskDefinition, candidate.taskDefinition)
+	fmt.Fprintf(table, "exit code\t%d\t%d\n", baseline.exitCode, candidate.exitCode)
+	fmt.Fprintf(table, "duration\t%s\t%s\n", baseline.duration.Round(time.Second), candidate.duration.Round(time.Second))
+	for i, re := range patterns {
+		fmt.Fprintf(table, "/%s/\t%d\t%d\n", re, baseline.matches[i], candidate.matches[i])
+	}
+	table.Flush()
+
+	var failures []string
+	for _, run := range runs {
+		if run.err != nil {
+			failures = append(failures, fmt.Sprintf("%s could not be run: %v", run.label, run.err))
+		}
+	}
+	if candidate.exitCode != baseline.exitCode {
+		failures = append(failures, fmt.Sprintf("candidate exited with %d, baseline with %d", candidate.exitCode, baseline.exitCode))
+	}
+	if canaryMaxSlowdown > 0 && float64(candidate.duration) > canaryMaxSlowdown*float64(baseline.duration) {
+		failures = append(failures, fmt.Sprintf("candidate took more than %g times as long as baseline", canaryMaxSlowdown))
+	}
+	for i, re := range patterns {
+		if candidate.matches[i] > baseline.matches[i] {
+			failures = append(failures, fmt.Sprintf("/%s/ matched %d times, %d in baseline", re, candidate.matches[i], baseline.matches[i]))
+		}
+	}
+	for _, failure := range failures {
+		fmt.Println(colorize(colorRed, "-"), failure)
+	}
+	if len(failures) > 0 {
+		printStatus(false, "candidate "+candidate.taskDefinition+" differs from baseline")
+		exit(1)
+	}
+	printStatus(true, "candidate "+candidate.taskDefinition+" matches baseline")
+	exit(0)
+}
+
+// lockCanary takes the locks of the baseline and the candidate, a single
+// one when they are of the same family, and holds them for the whole
+// canary. The runs are started without --lock-table.
+func lockCanary(ctx context.Context) {
+	cfg := newConfig(ctx)
+	ttl := 12 * time.Hour
+	if maxRunTime > 0 {
+		ttl = time.Duration(retries+1)*maxRunTime + 15*time.Minute
+	}
+	for _, definition := range []string{canaryBaseline, canaryCandidate} {
+		key := lockKey(ecsCluster, definition)
+		if definition == canaryCandidate && key == lockKey(ecsCluster, canaryBaseline) {
+			continue
+		}
+		if err := acquireLock(ctx, cfg, lockTable, key, ttl); err != nil {
+			exitWithError("Got error acquiring lock:", err)
+		}
+		fmt.Println("Acquired lock", key)
+		releaseLockOnExit(ctx, cfg, lockTable, key, lockOwner())
+	}
+}
+
+// canaryArgs returns the arguments that launch the run labeled label with
+// the flags set in flags, leaving out the flags only the canary command has
+// and the lock, which the canary holds. Files written by a run get the label
+// in their name, so the runs do not overwrite each other's.
+func canaryArgs(flags *pflag.FlagSet, label string) []string {
+	var args []string
+	flags.Visit(func(f *pflag.Flag) {
+		switch f.Name {
+		case "baseline", "candidate", "log-pattern", "max-slowdown", "task-definition", "yes", "lock-table",
+			"artifacts-dir", "task-arn-file", "log-file":
+			return
+		}
+		if slice, ok := f.Value.(pflag.SliceValue); ok {
+			for _, v := range slice.GetSlice() {
+				args = append(args, "--"+f.Name+"="+v)
+			}
+			return
+		}
+		args = append(args, "--"+f.Name+"="+f.Value.String())
+	})
+	args = append(args, "--artifacts-dir="+filepath.Join(artifactsDir, label))
+	if taskArnFile != "" {
+		args = append(args, "--task-arn-file="+labeledPath(taskArnFile, label))
+	}
+	if logFile != "" {
+		args = append(args, "--log-file="+labeledPath(logFile, label))
+	}
+	return args
+}
+
+// labeledPath returns path with label before its extension, e.g.
+// run.baseline.log for run.log.
+func labeledPath(path string, label string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + label + ext
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+var fargateSpot bool
+
+// capacityProviders are the --capacity-provider flags, each
+// name[:weight[:base]] or name[,weight[,base]].
+var capacityProviders []string
+
+// validateCapacityFlags exits if --capacity-provider is invalid, before
+// anything is locked or launched.
+func validateCapacityFlags() {
+	capacityProviderStrategy()
+}
+
+// capacityProviderStrategy returns the capacity provider strategy given by
+// --fargate-spot and --capacity-provider, nil to use the launch type.
+func capacityProviderStrategy() []ecstypes.CapacityProviderStrategyItem {
+	specs := capacityProviders
+	if fargateSpot {
+		specs = append([]string{"FARGATE_SPOT"}, specs...)
+	}
+	var strategy []ecstypes.CapacityProviderStrategyItem
+	withBase := 0
+	for _, spec := range specs {
+		item := parseCapacityProvider(spec)
+		if item.Base > 0 {
+			withBase++
+		}
+		strategy = append(strategy, item)
+	}
+	if withBase > 1 {
+		fmt.Println("Invalid --capacity-provider, only one capacity provider can have a base")
+		exit(1)
+	}
+	return strategy
+}
+
+func parseCapacityProvider(spec string) ecstypes.CapacityProviderStrategyItem {
+	sep := ","
+	if strings.Contains(spec, ":") {
+		sep = ":"
+	}
+	// Empty fields keep their default, so FARGATE,,2 has a base of 2.
+	parts := strings.Split(spec, sep)
+	item := ecstypes.CapacityProviderStrategyItem{CapacityProvider: aws.String(parts[0]), Weight: 1}
+	valid := parts[0] != "" && len(parts) <= 3
+	if valid && len(parts) > 1 && parts[1] != "" {
+		weight, err := strconv.ParseInt(parts[1], 10, 32)
+		valid = err == nil && weight >= 0 && weight <= 1000
+		item.Weight = int32(weight)
+	}
+	if valid && len(parts) > 2 && parts[2] != "" {
+		base, err := strconv.ParseInt(parts[2], 10, 32)
+		valid = err == nil && base >= 0 && base <= 100000
+		item.Base = int32(base)
+	}
+	if !valid {
+		fmt.Printf("Invalid --capacity-provider %q, expected name[,weight[,base]] with a weight of 0 to 1000 and a base of 0 to 100000\n", spec)
+		exit(1)
+	}
+	return item
+}
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestParseCapacityProvider(t *testing.T) {
+	tests := []struct {
+		spec   string
+		name   string
+		weight int32
+		base   int32
+	}{
+		{"FARGATE", "FARGATE", 1, 0},
+		{"FARGATE_SPOT,3", "FARGATE_SPOT", 3, 0},
+		{"FARGATE,1,1", "FARGATE", 1, 1},
+		{"FARGATE,0", "FARGATE", 0, 0},
+		{"FARGATE,,2", "FARGATE", 1, 2},
+		{"FARGATE_SPOT:3", "FARGATE_SPOT", 3, 0},
+		{"my-asg-provider:2:5", "my-asg-provider", 2, 5},
+		{"FARGATE,1000,100000", "FARGATE", 1000, 100000},
+	}
+	for _, test := range tests {
+		item := parseCapacityProvider(test.spec)
+		if name := aws.ToString(item.CapacityProvider); name != test.name || item.Weight != test.weight || item.Base != test.base {
+			t.Errorf("parseCapacityProvider(%q) = %s,%d,%d, want %s,%d,%d", test.spec, name, item.Weight, item.Base, test.name, test.weight, test.base)
+		}
+	}
+}
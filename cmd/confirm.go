@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+var assumeYes bool
+
+// confirmProtectedCluster asks the user to type the cluster name before
+// launching into a cluster listed under protected-clusters in the config file.
+// Without a terminal to ask on, --yes is required.
+func confirmProtectedCluster(cluster string) {
+	if assumeYes || !isProtectedCluster(cluster) {
+		return
+	}
+	if !isTerminal(os.Stdin) {
+		fmt.Printf("Cluster %s is protected, pass --yes to run tasks in it non-interactively\n", cluster)
+		exit(1)
+	}
+	fmt.Printf("Cluster %s is protected. Type the cluster name to continue: ", cluster)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if strings.TrimSpace(answer) != clusterName(cluster) {
+		fmt.Println("Aborted")
+		exit(1)
+	}
+}
+
+func isProtectedCluster(cluster string) bool {
+	for _, protected := range viper.GetStringSlice("protected-clusters") {
+		if clusterName(protected) == clusterName(cluster) {
+			return true
+		}
+	}
+	return false
+}
+
+// clusterName returns the short name of a cluster given either its name or ARN.
+func clusterName(cluster string) string {
+	return cluster[strings.LastIndex(cluster, "/")+1:]
+}
+
+func isTerminal(f *os.File) bool {
+	stat, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return stat.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,358 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	ststypes "github.com/aws/aws-sdk-go-v2/service/sts/types"
+)
+
+// defaultRoleSessionName is the session name of assumed roles, as shown in
+// CloudTrail.
+const defaultRoleSessionName = "ecs-run-task"
+
+// defaultRoleDuration is how long credentials of --role-arn are valid. It is
+// the longest duration every role allows, so long waits renew them rarely.
+const defaultRoleDuration = time.Hour
+
+var roleArn string
+var externalID string
+var roleSessionName string
+var sessionTags []string
+var mfaSerial string
+var mfaTokenCode string
+var roleDuration time.Duration
+
+// mfaUsed is set once an MFA token was asked for, so the credentials it
+// got are cached for later commands.
+var mfaUsed bool
+
+// assumedCredentials are the credentials of --role-arn and githubCredentials
+// those of a GitHub Actions job, shared by all configurations so roles are
+// assumed once and refreshed when they expire.
+var assumedCredentials *aws.CredentialsCache
+var githubCredentials *aws.CredentialsCache
+var assumedCredentialsMu sync.Mutex
+
+// initCredentials exits if a flag to assume a role is used without
+// --role-arn or has an invalid value.
+func initCredentials() {
+	if roleArn == "" && (externalID != "" || roleSessionName != "" || len(sessionTags) > 0 || mfaSerial != "") {
+		fmt.Println("--external-id, --session-name, --session-tag and --mfa-serial need --role-arn")
+		os.Exit(1)
+	}
+	for _, tag := range sessionTags {
+		splitKeyValue("session-tag", tag)
+	}
+	if roleDuration != 0 && (roleDuration < 15*time.Minute || roleDuration > 12*time.Hour) {
+		fmt.Println("--role-duration must be between 15m and 12h")
+		os.Exit(1)
+	}
+}
+
+// awsConfig returns the configuration of the shared config in region, the
+// configured one if region is empty, using the credentials of --role-arn if
+// it is set. Credentials that needed an MFA token are cached, see
+// cachedProvider.
+//
+// Temporary credentials are renewed before they expire, and requests
+// failing with ExpiredToken are retried with renewed ones, see apiRetryer,
+// so waits and log streams can outlive a single STS session.
+func awsConfig(ctx context.Context, region string) aws.Config {
+	options := []func(*config.LoadOptions) error{
+		config.WithHTTPClient(newAWSHTTPClient()),
+		config.WithAssumeRoleCredentialOptions(func(o *stscreds.AssumeRoleOptions) {
+			o.TokenProvider = mfaToken
+			if roleDuration != 0 {
+				o.Duration = roleDuration
+			}
+		}),
+	}
+	if region != "" {
+		options = append(options, config.WithRegion(region))
+	}
+	if caBundlePEM != nil {
+		options = append(options, config.WithCustomCABundle(bytes.NewReader(caBundlePEM)))
+	}
+	if endpointURL != "" {
+		options = append(options, config.WithBaseEndpoint(endpointURL))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, options...)
+	if err != nil {
+		exitWithError("Got error loading the AWS configuration:", err)
+	}
+	provider := &cachedProvider{key: credentialsCacheKey()}
+	credentials := aws.NewCredentialsCache(provider, func(o *aws.CredentialsCacheOptions) {
+		o.ExpiryWindow = 5 * time.Minute
+	})
+	cfg.Retryer = apiRetryer(credentials)
+	addServiceEndpoints(&cfg)
+	debugConfig(&cfg)
+	limitRequests(&cfg)
+	if creds := githubWebIdentity(cfg); creds != nil {
+		cfg.Credentials = creds
+	}
+	if roleArn != "" {
+		cfg.Credentials = roleCredentials(cfg)
+	}
+	provider.credentials = cfg.Credentials
+	cfg.Credentials = credentials
+	return cfg
+}
+
+// roleCredentials returns the credentials of --role-arn, assumed with the
+// credentials of cfg the first time they are needed.
+func roleCredentials(cfg aws.Config) *aws.CredentialsCache {
+	assumedCredentialsMu.Lock()
+	defer assumedCredentialsMu.Unlock()
+	if assumedCredentials != nil {
+		return assumedCredentials
+	}
+	assumedCredentials = aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), roleArn, func(o *stscreds.AssumeRoleOptions) {
+		o.RoleSessionName, o.Duration = defaultRoleSessionName, defaultRoleDuration
+		if roleDuration != 0 {
+			o.Duration = roleDuration
+		}
+		if roleSessionName != "" {
+			o.RoleSessionName = roleSessionName
+		}
+		if externalID != "" {
+			o.ExternalID = aws.String(externalID)
+		}
+		if mfaSerial != "" {
+			o.SerialNumber, o.TokenProvider = aws.String(mfaSerial), mfaToken
+		}
+		for _, tag := range sessionTags {
+			key, value := splitKeyValue("session-tag", tag)
+			o.Tags = append(o.Tags, ststypes.Tag{Key: aws.String(key), Value: aws.String(value)})
+		}
+	}))
+	return assumedCredentials
+}
+
+// githubWebIdentity returns credentials of the role in AWS_ROLE_ARN for the
+// OIDC token of a GitHub Actions job, or nil outside of GitHub Actions. The
+// SDK itself only assumes roles with web identity when the token is in the
+// file AWS_WEB_IDENTITY_TOKEN_FILE, as in EKS pods, which GitHub does not
+// write. The job needs the id-token: write permission.
+func githubWebIdentity(cfg aws.Config) *aws.CredentialsCache {
+	role := os.Getenv("AWS_ROLE_ARN")
+	if role == "" || os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE") != "" || os.Getenv("AWS_ACCESS_KEY_ID") != "" ||
+		os.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL") == "" {
+		return nil
+	}
+	assumedCredentialsMu.Lock()
+	defer assumedCredentialsMu.Unlock()
+	if githubCredentials != nil {
+		return githubCredentials
+	}
+	name := os.Getenv("AWS_ROLE_SESSION_NAME")
+	if name == "" {
+		name = defaultRoleSessionName
+	}
+	debugf("assuming %s with the OIDC token of the GitHub Actions job", role)
+	githubCredentials = aws.NewCredentialsCache(stscreds.NewWebIdentityRoleProvider(sts.NewFromConfig(cfg), role, githubTokenFetcher{}, func(o *stscreds.WebIdentityRoleOptions) {
+		o.RoleSessionName = name
+	}))
+	return githubCredentials
+}
+
+// githubTokenFetcher fetches the OIDC token of a GitHub Actions job for
+// AWS STS.
+type githubTokenFetcher struct{}
+
+func (githubTokenFetcher) GetIdentityToken() ([]byte, error) {
+	u, err := url.Parse(os.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL"))
+	if err != nil {
+		return nil, err
+	}
+	query := u.Query()
+	query.Set("audience", "sts.amazonaws.com")
+	u.RawQuery = query.Encode()
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "bearer "+os.Getenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN"))
+	resp, err := newHTTPClient(30 * time.Second).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("requesting the GitHub Actions OIDC token returned %s", resp.Status)
+	}
+	var token struct {
+		Value string `json:"value"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return nil, err
+	}
+	return []byte(token.Value), nil
+}
+
+// mfaToken returns the MFA token code of --mfa-token, or asks for one on
+// the terminal.
+func mfaToken() (string, error) {
+	mfaUsed = true
+	if mfaTokenCode != "" {
+		return mfaTokenCode, nil
+	}
+	if !isTerminal(os.Stdin) {
+		return "", fmt.Errorf("the credentials need an MFA token, pass it with --mfa-token")
+	}
+	fmt.Fprint(os.Stderr, "MFA token code: ")
+	code, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	return strings.TrimSpace(code), err
+}
+
+// awsProfile returns the name of the selected profile, "" for the default
+// profile.
+func awsProfile() string {
+	if profile := os.Getenv("AWS_PROFILE"); profile != "" {
+		return profile
+	}
+	return os.Getenv("AWS_DEFAULT_PROFILE")
+}
+
+// ssoErrorPatterns are messages of credential errors caused by a missing or
+// expired IAM Identity Center (SSO) login.
+var ssoErrorPatterns = []string{
+	"the SSO session has expired or is invalid",
+	"failed to read cached SSO token file",
+	"Session token not found or invalid",
+	"InvalidGrantException",
+}
+
+// printSSOHint tells how to log in again when text is an error of an
+// expired IAM Identity Center session.
+func printSSOHint(text string) {
+	for _, pattern := range ssoErrorPatterns {
+		if !strings.Contains(text, pattern) {
+			continue
+		}
+		command := "aws sso login"
+		if profile := awsProfile(); profile != "" && profile != "default" {
+			command += " --profile " + profile
+		}
+		fmt.Println(colorize(colorYellow, "Hint:"), "Your AWS IAM Identity Center (SSO) session has expired or you are not logged in. Log in with:", command)
+		fmt.Println("See: https://docs.aws.amazon.com/cli/latest/userguide/sso-using-profile.html")
+		return
+	}
+}
+
+// credentialsCacheKey identifies the credentials of the current profile,
+// access key and --role-arn in the cache, together with everything else the
+// role is assumed with.
+func credentialsCacheKey() string {
+	parts := []string{awsProfile(), os.Getenv("AWS_ACCESS_KEY_ID"), roleArn, mfaSerial,
+		externalID, roleSessionName, roleDuration.String()}
+	parts = append(parts, sessionTags...)
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// credentialsCacheFile returns the file credentials with key are cached
+// in, below ~/.ecs-run-task/cache.
+func credentialsCacheFile(key string) string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".ecs-run-task", "cache", "credentials-"+key+".json")
+}
+
+// cachedCredentials are temporary credentials as cached on disk.
+type cachedCredentials struct {
+	AccessKeyID     string    `json:"accessKeyId"`
+	SecretAccessKey string    `json:"secretAccessKey"`
+	SessionToken    string    `json:"sessionToken"`
+	Expiration      time.Time `json:"expiration"`
+}
+
+// cachedProvider provides credentials from the cache while they are valid
+// and from the wrapped credentials otherwise. Only credentials that needed
+// an MFA token are written to the cache, so later commands do not ask for
+// another token until they expire.
+//
+// Once credentials were retrieved, every further retrieval renews them:
+// either they expired, or they were invalidated because AWS rejected them
+// with ExpiredToken. The cache and the wrapped credentials would return the
+// rejected ones again.
+type cachedProvider struct {
+	key         string
+	credentials aws.CredentialsProvider
+	retrieved   bool
+}
+
+func (p *cachedProvider) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	creds, err := p.retrieve(ctx)
+	if err == nil {
+		p.retrieved = true
+	}
+	return creds, err
+}
+
+func (p *cachedProvider) retrieve(ctx context.Context) (aws.Credentials, error) {
+	if p.retrieved {
+		debugf("renewing expired AWS credentials")
+		os.Remove(credentialsCacheFile(p.key))
+		if cache, ok := p.credentials.(*aws.CredentialsCache); ok {
+			cache.Invalidate()
+		}
+	} else if data, err := ioutil.ReadFile(credentialsCacheFile(p.key)); err == nil {
+		var cached cachedCredentials
+		if json.Unmarshal(data, &cached) == nil && time.Now().Before(cached.Expiration.Add(-5*time.Minute)) {
+			return aws.Credentials{
+				AccessKeyID:     cached.AccessKeyID,
+				SecretAccessKey: cached.SecretAccessKey,
+				SessionToken:    cached.SessionToken,
+				Source:          "ecs-run-task cache",
+				CanExpire:       true,
+				Expires:         cached.Expiration,
+			}, nil
+		}
+	}
+	creds, err := p.credentials.Retrieve(ctx)
+	if err != nil {
+		return creds, err
+	}
+	// Credentials that do not expire are never cached.
+	if creds.CanExpire && mfaUsed {
+		if err := writeCredentialsCache(p.key, creds); err != nil {
+			printError("Got error caching credentials:", err)
+		}
+	}
+	return creds, nil
+}
+
+func writeCredentialsCache(key string, creds aws.Credentials) error {
+	data, err := json.Marshal(cachedCredentials{
+		AccessKeyID:     creds.AccessKeyID,
+		SecretAccessKey: creds.SecretAccessKey,
+		SessionToken:    creds.SessionToken,
+		Expiration:      creds.Expires,
+	})
+	if err != nil {
+		return err
+	}
+	path := credentialsCacheFile(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
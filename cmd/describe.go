@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/spf13/cobra"
+)
+
+var describeOutput string
+
+// taskDescription are the details of a task describe prints.
+type taskDescription struct {
+	TaskID           string                 `json:"taskId"`
+	TaskArn          string                 `json:"taskArn"`
+	Cluster          string                 `json:"cluster"`
+	TaskDefinition   string                 `json:"taskDefinition"`
+	LastStatus       string                 `json:"lastStatus"`
+	DesiredStatus    string                 `json:"desiredStatus"`
+	LaunchType       string                 `json:"launchType,omitempty"`
+	CapacityProvider string                 `json:"capacityProvider,omitempty"`
+	PlatformVersion  string                 `json:"platformVersion,omitempty"`
+	StartedBy        string                 `json:"startedBy,omitempty"`
+	Group            string                 `json:"group,omitempty"`
+	StopCode         string                 `json:"stopCode,omitempty"`
+	StoppedReason    string                 `json:"stoppedReason,omitempty"`
+	StopCategory     stopCategory           `json:"stopCategory,omitempty"`
+	NetworkInterface string                 `json:"networkInterface,omitempty"`
+	PrivateIP        string                 `json:"privateIp,omitempty"`
+	PublicIP         string                 `json:"publicIp,omitempty"`
+	Times            []taskTime             `json:"times"`
+	Containers       []containerDescription `json:"containers"`
+}
+
+type taskTime struct {
+	Name string    `json:"name"`
+	Time time.Time `json:"time"`
+}
+
+type containerDescription struct {
+	Name       string `json:"name"`
+	Image      string `json:"image"`
+	LastStatus string `json:"lastStatus"`
+	ExitCode   *int32 `json:"exitCode,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+	PrivateIP  string `json:"privateIp,omitempty"`
+}
+
+// describeCmd prints the details of a task
+var describeCmd = &cobra.Command{
+	Use:   "describe <task-arn>",
+	Short: "Print the details of a task",
+	Long: `Print the status, exit codes, stop reason, network addresses, capacity
+provider and timings of a task and its containers.
+
+The task can be given as ARN, or as ID together with --cluster.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if describeOutput != "text" && describeOutput != "json" {
+			fmt.Println("Unknown output format:", describeOutput)
+			os.Exit(1)
+		}
+		ctx := cmd.Context()
+		cfg, cluster, _, task, err := findTask(ctx, args[0])
+		if err != nil {
+			exitWithError("Got error describing task:", err)
+		}
+		description := describeTask(cluster, task)
+		if description.NetworkInterface != "" && description.LastStatus != string(ecstypes.DesiredStatusStopped) {
+			description.PublicIP, err = publicIP(ctx, cfg, description.NetworkInterface)
+			if err != nil {
+				printError("Got error looking up public IP:", err)
+			}
+		}
+		if describeOutput == "json" {
+			out, _ := json.MarshalIndent(description, "", "  ")
+			fmt.Println(string(out))
+			return
+		}
+		printTaskDescription(description)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(describeCmd)
+	describeCmd.Flags().StringVarP(&ecsCluster, "cluster", "c", "", "Name of the Cluster, if the task is given by ID")
+	describeCmd.Flags().StringVarP(&describeOutput, "output", "o", "text", "Output format: text or json")
+}
+
+func describeTask(cluster string, task *ecstypes.Task) taskDescription {
+	d := taskDescription{
+		TaskID:           taskID(aws.ToString(task.TaskArn)),
+		TaskArn:          aws.ToString(task.TaskArn),
+		Cluster:          clusterName(cluster),
+		TaskDefinition:   taskDefinitionName(aws.ToString(task.TaskDefinitionArn)),
+		LastStatus:       aws.ToString(task.LastStatus),
+		DesiredStatus:    aws.ToString(task.DesiredStatus),
+		LaunchType:       string(task.LaunchType),
+		CapacityProvider: aws.ToString(task.CapacityProviderName),
+		PlatformVersion:  aws.ToString(task.PlatformVersion),
+		StartedBy:        aws.ToString(task.StartedBy),
+		Group:            aws.ToString(task.Group),
+		StopCode:         string(task.StopCode),
+		StoppedReason:    aws.ToString(task.StoppedReason),
+	}
+	if aws.ToString(task.LastStatus) == string(ecstypes.DesiredStatusStopped) {
+		d.StopCategory = classifyTask(task)
+	}
+	d.NetworkInterface, d.PrivateIP = taskNetworkInterface(task)
+	d.Times = taskTimes(task)
+	for _, c := range task.Containers {
+		container := containerDescription{
+			Name:       aws.ToString(c.Name),
+			Image:      aws.ToString(c.Image),
+			LastStatus: aws.ToString(c.LastStatus),
+			ExitCode:   c.ExitCode,
+			Reason:     aws.ToString(c.Reason),
+		}
+		for _, ni := range c.NetworkInterfaces {
+			container.PrivateIP = aws.ToString(ni.PrivateIpv4Address)
+		}
+		d.Containers = append(d.Containers, container)
+	}
+	return d
+}
+
+func printTaskDescription(d taskDescription) {
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	row := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(table, "%s:\t%s\n", name, value)
+		}
+	}
+	row("Task", d.TaskArn)
+	row("Cluster", d.Cluster)
+	row("Task definition", d.TaskDefinition)
+	row("Status", d.LastStatus+" (desired "+d.DesiredStatus+")")
+	row("Launch type", d.LaunchType)
+	row("Capacity provider", d.CapacityProvider)
+	row("Platform version", d.PlatformVersion)
+	row("Started by", d.StartedBy)
+	row("Group", d.Group)
+	row("Stop code", d.StopCode)
+	row("Stop reason", d.StoppedReason)
+	row("Stop category", string(d.StopCategory))
+	row("Network interface", d.NetworkInterface)
+	row("Private IP", d.PrivateIP)
+	row("Public IP", d.PublicIP)
+	var previous time.Time
+	for _, t := range d.Times {
+		value := t.Time.Format(time.RFC3339)
+		if !previous.IsZero() {
+			value += fmt.Sprintf(" (+%s)", t.Time.Sub(previous).Round(time.Second))
+		}
+		row("Time "+t.Name, value)
+		previous = t.Time
+	}
+	table.Flush()
+
+	fmt.Println("Containers:")
+	table = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "NAME\tSTATUS\tEXIT CODE\tIMAGE\tREASON")
+	for _, c := range d.Containers {
+		exitCode := ""
+		if c.ExitCode != nil {
+			exitCode = fmt.Sprint(*c.ExitCode)
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\n", c.Name, c.LastStatus, exitCode, c.Image, c.Reason)
+	}
+	table.Flush()
+}
@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/spf13/cobra"
+)
+
+// diffRevisionsCmd shows what changed between two task definitions
+var diffRevisionsCmd = &cobra.Command{
+	Use:   "diff-revisions <old> <new>",
+	Short: "Show what changed between two task definition revisions",
+	Long: `Show the differences in image, environment variables, secrets, cpu,
+memory and roles between two registered task definitions, e.g.
+
+  ecs-run-task diff-revisions report:11 report:12
+
+Values of environment variables are shown, secrets only by where they are
+read from.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		cfg := newConfig(ctx)
+		old := describeTaskDefinition(ctx, cfg, args[0])
+		new := describeTaskDefinition(ctx, cfg, args[1])
+		if !printTaskDefinitionDiff(old, new) {
+			fmt.Println("No differences")
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(diffRevisionsCmd)
+}
+
+func describeTaskDefinition(ctx context.Context, cfg aws.Config, taskDefinition string) *ecstypes.TaskDefinition {
+	output, err := ecs.NewFromConfig(cfg).DescribeTaskDefinition(ctx, &ecs.DescribeTaskDefinitionInput{
+		TaskDefinition: aws.String(taskDefinition),
+	})
+	if err != nil {
+		exitWithError("Got error describing task definition "+taskDefinition+":", err)
+	}
+	return output.TaskDefinition
+}
+
+// printTaskDefinitionDiff prints the settings that differ between old and
+// new and reports whether there were any.
+func printTaskDefinitionDiff(old, new *ecstypes.TaskDefinition) bool {
+	fmt.Printf("--- %s\n+++ %s\n", aws.ToString(old.TaskDefinitionArn), aws.ToString(new.TaskDefinitionArn))
+	changed := false
+	changed = printValueDiff("cpu", aws.ToString(old.Cpu), aws.ToString(new.Cpu)) || changed
+	changed = printValueDiff("memory", aws.ToString(old.Memory), aws.ToString(new.Memory)) || changed
+	changed = printValueDiff("taskRoleArn", aws.ToString(old.TaskRoleArn), aws.ToString(new.TaskRoleArn)) || changed
+	changed = printValueDiff("executionRoleArn", aws.ToString(old.ExecutionRoleArn), aws.ToString(new.ExecutionRoleArn)) || changed
+
+	oldContainers := containersByName(old)
+	newContainers := containersByName(new)
+	for _, name := range containerNames(old, new) {
+		o, n := oldContainers[name], newContainers[name]
+		switch {
+		case o == nil:
+			fmt.Println(colorize(colorGreen, "+ container "+name))
+			changed = true
+			continue
+		case n == nil:
+			fmt.Println(colorize(colorRed, "- container "+name))
+			changed = true
+			continue
+		}
+		prefix := "container " + name + " "
+		changed = printValueDiff(prefix+"image", aws.ToString(o.Image), aws.ToString(n.Image)) || changed
+		changed = printValueDiff(prefix+"cpu", formatInt(o.Cpu), formatInt(n.Cpu)) || changed
+		changed = printValueDiff(prefix+"memory", formatInt(aws.ToInt32(o.Memory)), formatInt(aws.ToInt32(n.Memory))) || changed
+		changed = printValueDiff(prefix+"memoryReservation", formatInt(aws.ToInt32(o.MemoryReservation)), formatInt(aws.ToInt32(n.MemoryReservation))) || changed
+		oldEnv, newEnv := environmentMap(o), environmentMap(n)
+		for _, key := range unionKeys(oldEnv, newEnv) {
+			changed = printValueDiff(prefix+"env "+key, oldEnv[key], newEnv[key]) || changed
+		}
+		oldSecrets, newSecrets := secretsMap(o), secretsMap(n)
+		for _, key := range unionKeys(oldSecrets, newSecrets) {
+			changed = printValueDiff(prefix+"secret "+key, oldSecrets[key], newSecrets[key]) || changed
+		}
+	}
+	return changed
+}
+
+// printValueDiff prints the setting name if old and new differ and reports
+// whether they did. An empty value means the setting is not set.
+func printValueDiff(name, old, new string) bool {
+	if old == new {
+		return false
+	}
+	if old != "" {
+		fmt.Println(colorize(colorRed, "- "+name+": "+old))
+	}
+	if new != "" {
+		fmt.Println(colorize(colorGreen, "+ "+name+": "+new))
+	}
+	return true
+}
+
+func containersByName(td *ecstypes.TaskDefinition) map[string]*ecstypes.ContainerDefinition {
+	containers := map[string]*ecstypes.ContainerDefinition{}
+	for i, c := range td.ContainerDefinitions {
+		containers[aws.ToString(c.Name)] = &td.ContainerDefinitions[i]
+	}
+	return containers
+}
+
+func environmentMap(c *ecstypes.ContainerDefinition) map[string]string {
+	env := map[string]string{}
+	for _, kv := range c.Environment {
+		env[aws.ToString(kv.Name)] = aws.ToString(kv.Value)
+	}
+	return env
+}
+
+func secretsMap(c *ecstypes.ContainerDefinition) map[string]string {
+	secrets := map[string]string{}
+	for _, s := range c.Secrets {
+		secrets[aws.ToString(s.Name)] = aws.ToString(s.ValueFrom)
+	}
+	return secrets
+}
+
+// formatInt formats a setting of a container, 0 if it is not set.
+func formatInt(v int32) string {
+	if v == 0 {
+		return ""
+	}
+	return strconv.FormatInt(int64(v), 10)
+}
+
+// containerNames returns the names of the containers of old and new, in
+// the order of old with containers only new has last.
+func containerNames(old, new *ecstypes.TaskDefinition) []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, td := range []*ecstypes.TaskDefinition{old, new} {
+		for _, c := range td.ContainerDefinitions {
+			if name := aws.ToString(c.Name); !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// unionKeys returns the keys of a and b in sorted order.
+func unionKeys(a, b map[string]string) []string {
+	var keys []string
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
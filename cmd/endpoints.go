@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+var endpointURL string
+var serviceEndpointFlags []string
+
+// serviceEndpoints are the endpoints of --service-endpoint by endpoint ID
+// of the service, e.g. "ecs" or "logs".
+var serviceEndpoints = map[string]string{}
+
+// initEndpoints exits if --endpoint-url or --service-endpoint is not an
+// absolute URL.
+func initEndpoints() {
+	if endpointURL != "" {
+		checkEndpointURL("endpoint-url", endpointURL)
+	}
+	for _, spec := range serviceEndpointFlags {
+		service, u := splitKeyValue("service-endpoint", spec)
+		checkEndpointURL("service-endpoint", u)
+		serviceEndpoints[strings.ToLower(service)] = u
+	}
+}
+
+func checkEndpointURL(flag string, s string) {
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		fmt.Printf("Invalid --%s %q, expected a URL like https://ecs.example.com or http://localhost:4566\n", flag, s)
+		os.Exit(1)
+	}
+}
+
+// customEndpoint returns the endpoint given for service, "" if it has the
+// default one.
+func customEndpoint(service string) string {
+	if u, ok := serviceEndpoints[service]; ok {
+		return u
+	}
+	return endpointURL
+}
+
+// endpointIDs are the endpoint IDs of services whose SDK ID is not their
+// endpoint ID without spaces, e.g. "Secrets Manager".
+var endpointIDs = map[string]string{
+	"CloudWatch":      "monitoring",
+	"CloudWatch Logs": "logs",
+}
+
+// serviceEndpointSource is a source of the configuration of cfg providing
+// the endpoints of --service-endpoint. It takes precedence over
+// --endpoint-url, AWS_ENDPOINT_URL_<SERVICE> and the shared config.
+// Requests to custom endpoints are still signed for the service and region,
+// which VPC endpoints and emulators like LocalStack expect.
+type serviceEndpointSource struct{}
+
+func (serviceEndpointSource) GetServiceBaseEndpoint(ctx context.Context, sdkID string) (string, bool, error) {
+	id, ok := endpointIDs[sdkID]
+	if !ok {
+		id = strings.ToLower(strings.Replace(sdkID, " ", "", -1))
+	}
+	u, ok := serviceEndpoints[id]
+	return u, ok, nil
+}
+
+// addServiceEndpoints makes the clients of cfg use the endpoints of
+// --service-endpoint.
+func addServiceEndpoints(cfg *aws.Config) {
+	if len(serviceEndpoints) > 0 {
+		cfg.ConfigSources = append([]interface{}{serviceEndpointSource{}}, cfg.ConfigSources...)
+	}
+	if len(serviceEndpoints) > 0 || endpointURL != "" {
+		// Custom hosts have no variants like the streaming- host of Live Tail.
+		cfg.APIOptions = append(cfg.APIOptions, func(stack *middleware.Stack) error {
+			return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("DisableEndpointHostPrefix", disableHostPrefix), middleware.Before)
+		})
+	}
+}
+
+func disableHostPrefix(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+	return next.HandleInitialize(smithyhttp.DisableEndpointHostPrefix(ctx, true), in)
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+)
+
+var envVars []string
+var envFiles []string
+var secretEnvs []string
+var ssmEnvs []string
+
+// readEnvFile parses a dotenv formatted file into name/value pairs, keeping
+// the order of the file. Blank lines, # comments and a leading "export "
+// are ignored. Double quoted values support the usual escapes, single quoted
+// values are taken literally.
+func readEnvFile(path string) ([][2]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var vars [][2]string
+	scanner := bufio.NewScanner(f)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		parts := strings.SplitN(line, "=", 2)
+		name := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || name == "" {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE", path, lineNumber)
+		}
+		value := strings.TrimSpace(parts[1])
+		switch {
+		case strings.HasPrefix(value, `"`):
+			value, err = strconv.Unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid quoted value for %s", path, lineNumber, name)
+			}
+		case strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'") && len(value) > 1:
+			value = value[1 : len(value)-1]
+		default:
+			if i := strings.Index(value, " #"); i >= 0 {
+				value = strings.TrimSpace(value[:i])
+			}
+		}
+		vars = append(vars, [2]string{name, value})
+	}
+	return vars, scanner.Err()
+}
+
+// splitKeyValue splits a KEY=VALUE flag value.
+func splitKeyValue(flag string, spec string) (string, string) {
+	parts := strings.SplitN(spec, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		fmt.Printf("Invalid --%s %q, expected KEY=VALUE\n", flag, spec)
+		exit(1)
+	}
+	return parts[0], parts[1]
+}
+
+// addEnvVar applies an --env flag, KEY=VALUE for every container or
+// container:KEY=VALUE for a single one.
+func addEnvVar(spec string) {
+	name, value := splitKeyValue("env", spec)
+	if i := strings.Index(name, ":"); i >= 0 {
+		addContainerEnvOverride(name[:i], name[i+1:], value)
+		return
+	}
+	addEnvOverride(name, value)
+}
+
+// resolveSecret fetches the value of a Secrets Manager secret. ref is a
+// secret name or ARN, optionally followed by :json-key:version-stage:version-id
+// like the valueFrom of secrets in task definitions.
+func resolveSecret(ctx context.Context, cfg aws.Config, ref string) (string, error) {
+	input := &secretsmanager.GetSecretValueInput{SecretId: aws.String(ref)}
+	var jsonKey string
+	if strings.HasPrefix(ref, "arn:") {
+		parts := strings.Split(ref, ":")
+		if len(parts) > 7 {
+			input.SecretId = aws.String(strings.Join(parts[:7], ":"))
+			jsonKey = parts[7]
+			if len(parts) > 8 && parts[8] != "" {
+				input.VersionStage = aws.String(parts[8])
+			}
+			if len(parts) > 9 && parts[9] != "" {
+				input.VersionId = aws.String(parts[9])
+			}
+		}
+	}
+	output, err := secretsmanager.NewFromConfig(cfg).GetSecretValue(ctx, input)
+	if err != nil {
+		return "", err
+	}
+	if output.SecretString == nil {
+		return "", fmt.Errorf("secret %s has no string value", *input.SecretId)
+	}
+	if jsonKey == "" {
+		return *output.SecretString, nil
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal([]byte(*output.SecretString), &values); err != nil {
+		return "", fmt.Errorf("secret %s is not a JSON object: %v", *input.SecretId, err)
+	}
+	value, ok := values[jsonKey]
+	if !ok {
+		return "", fmt.Errorf("secret %s has no key %q", *input.SecretId, jsonKey)
+	}
+	if str, ok := value.(string); ok {
+		return str, nil
+	}
+	encoded, _ := json.Marshal(value)
+	return string(encoded), nil
+}
+
+// resolveParameter fetches the value of an SSM parameter, decrypting
+// SecureString parameters. name is a parameter name or ARN.
+func resolveParameter(ctx context.Context, cfg aws.Config, name string) (string, error) {
+	output, err := ssm.NewFromConfig(cfg).GetParameter(ctx, &ssm.GetParameterInput{
+		Name:           aws.String(name),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		return "", err
+	}
+	return *output.Parameter.Value, nil
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadEnvFile(t *testing.T) {
+	tests := []struct {
+		content string
+		want    [][2]string
+	}{
+		{"A=1\nB=2\n", [][2]string{{"A", "1"}, {"B", "2"}}},
+		{"\n# comment\n  \nA=1", [][2]string{{"A", "1"}}},
+		{"export A=1", [][2]string{{"A", "1"}}},
+		{" A = 1 ", [][2]string{{"A", "1"}}},
+		{"A=", [][2]string{{"A", ""}}},
+		{"A=a=b", [][2]string{{"A", "a=b"}}},
+		{"A=1 # comment", [][2]string{{"A", "1"}}},
+		{"A=1#2", [][2]string{{"A", "1#2"}}},
+		{`A="a b # c\n"`, [][2]string{{"A", "a b # c\n"}}},
+		{`A='a\nb $c'`, [][2]string{{"A", `a\nb $c`}}},
+		{"B=2\nA=1", [][2]string{{"B", "2"}, {"A", "1"}}},
+	}
+	for _, test := range tests {
+		got, err := readEnvFile(writeEnvFile(t, test.content))
+		if err != nil {
+			t.Errorf("readEnvFile(%q) returned error: %v", test.content, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("readEnvFile(%q) = %q, want %q", test.content, got, test.want)
+		}
+	}
+}
+
+func TestReadEnvFileErrors(t *testing.T) {
+	for _, content := range []string{"A", "=1", `A="unterminated`} {
+		if got, err := readEnvFile(writeEnvFile(t, content)); err == nil {
+			t.Errorf("readEnvFile(%q) = %q, want an error", content, got)
+		}
+	}
+}
+
+func writeEnvFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// errorHint is a suggestion shown when an error message contains pattern.
+type errorHint struct {
+	pattern string
+	hint    string
+	doc     string
+}
+
+// errorHints lists known failure messages from AWS and how to fix them.
+// Patterns are matched case-insensitively.
+var errorHints = []errorHint{
+	{
+		pattern: "TargetNotConnectedException",
+		hint:    "The SSM agent in the task is not connected. Allow the ssmmessages:CreateControlChannel, CreateDataChannel, OpenControlChannel and OpenDataChannel actions in the task role, and give the task a route to the SSM endpoints.",
+		doc:     "https://docs.aws.amazon.com/AmazonECS/latest/developerguide/ecs-exec.html",
+	},
+	{
+		pattern: "ecr:GetAuthorizationToken",
+		hint:    "The task execution role is not allowed to pull images from ECR. Attach the AmazonECSTaskExecutionRolePolicy managed policy to it.",
+		doc:     "https://docs.aws.amazon.com/AmazonECS/latest/developerguide/task_execution_IAM_role.html",
+	},
+	{
+		pattern: "log group does not exist",
+		hint:    "The log group in the awslogs-group option does not exist. Create it, pass --create-log-group, or set awslogs-create-group to true in the log configuration.",
+		doc:     "https://docs.aws.amazon.com/AmazonECS/latest/developerguide/using_awslogs.html",
+	},
+	{
+		pattern: "log stream does not exist",
+		hint:    "The container has not written any logs. Check that it started and that awslogs-stream-prefix is set in the log configuration.",
+		doc:     "https://docs.aws.amazon.com/AmazonECS/latest/developerguide/using_awslogs.html",
+	},
+	{
+		pattern: "Error retrieving subnet information",
+		hint:    "Check --subnets: every subnet must exist in the account and region the task is launched in.",
+		doc:     "https://docs.aws.amazon.com/AmazonECS/latest/developerguide/task-networking.html",
+	},
+	{
+		pattern: "Error retrieving security group information",
+		hint:    "Check --security-groups: every security group must exist in the VPC of the subnets.",
+		doc:     "https://docs.aws.amazon.com/AmazonECS/latest/developerguide/task-networking.html",
+	},
+	{
+		pattern: "Network Configuration must be provided",
+		hint:    "Task definitions using the awsvpc network mode need --subnets (and usually --security-groups).",
+		doc:     "https://docs.aws.amazon.com/AmazonECS/latest/developerguide/task-networking.html",
+	},
+	{
+		pattern: "unable to pull secrets or registry auth",
+		hint:    "The task could not reach ECR or Secrets Manager. Tasks in private subnets need a NAT gateway or VPC endpoints, tasks in public subnets need a public IP.",
+		doc:     "https://docs.aws.amazon.com/AmazonECS/latest/developerguide/task_cannot_pull_image.html",
+	},
+	{
+		pattern: "CannotPullContainerError",
+		hint:    "The image could not be pulled. Check the image name and tag, and that the execution role can access the registry. In public subnets without a NAT gateway pass --assign-public-ip.",
+		doc:     "https://docs.aws.amazon.com/AmazonECS/latest/developerguide/task_cannot_pull_image.html",
+	},
+	{
+		pattern: "ClusterNotFoundException",
+		hint:    "Check --cluster and that the region of your profile or AWS_REGION is the one the cluster lives in.",
+		doc:     "https://docs.aws.amazon.com/AmazonECS/latest/developerguide/clusters.html",
+	},
+	{
+		pattern: "to have execution role ARN",
+		hint:    "Fargate tasks need an execution role to write logs and pull private images. Pass --execution-role-arn or set executionRoleArn in the task definition.",
+		doc:     "https://docs.aws.amazon.com/AmazonECS/latest/developerguide/task_execution_IAM_role.html",
+	},
+	{
+		pattern: "iam:PassRole",
+		hint:    "Your credentials need iam:PassRole on the task role and execution role of the task definition.",
+		doc:     "https://docs.aws.amazon.com/AmazonECS/latest/developerguide/task-iam-roles.html",
+	},
+	{
+		pattern: "sts:AssumeRole",
+		hint:    "Check that the trust policy of --role-arn allows your identity, that --external-id matches its sts:ExternalId condition, and that it allows sts:TagSession when passing --session-tag.",
+		doc:     "https://docs.aws.amazon.com/IAM/latest/UserGuide/troubleshoot_roles.html",
+	},
+	{
+		pattern: "AssumeRoleWithWebIdentity",
+		hint:    "The role in AWS_ROLE_ARN could not be assumed with the web identity token. Check that its trust policy trusts the OIDC provider and matches the aud and sub claims of the token, e.g. the repository and branch of a GitHub Actions job.",
+		doc:     "https://docs.aws.amazon.com/IAM/latest/UserGuide/id_roles_create_for-idp_oidc.html",
+	},
+	{
+		pattern: "MultiFactorAuthentication failed",
+		hint:    "The MFA token code was wrong or has already been used. Wait for the next code of your device and try again.",
+		doc:     "https://docs.aws.amazon.com/IAM/latest/UserGuide/id_credentials_mfa_configure-api-require.html",
+	},
+	{
+		pattern: "no EC2 IMDS role found",
+		hint:    "No AWS credentials were found. Set AWS_PROFILE or AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY.",
+		doc:     "https://docs.aws.amazon.com/sdk-for-go/v2/developer-guide/configure-gosdk.html",
+	},
+	{
+		pattern: "Missing Region",
+		hint:    "No AWS region is configured. Set AWS_REGION or a region in your profile.",
+		doc:     "https://docs.aws.amazon.com/sdk-for-go/v2/developer-guide/configure-gosdk.html",
+	},
+	{
+		pattern: "ExpiredToken",
+		hint:    "Your AWS credentials have expired. Credentials of profiles, --role-arn and web identities are renewed automatically, but fixed AWS_SESSION_TOKEN credentials cannot be: for long runs use a profile or --role-arn, or raise the session duration of whatever issued them.",
+		doc:     "https://docs.aws.amazon.com/sdk-for-go/v2/developer-guide/configure-gosdk.html",
+	},
+}
+
+// cleanups run before the process exits through exit, in reverse order of
+// registration.
+var cleanups []func()
+
+// onExit registers f to run before the process exits through exit.
+func onExit(f func()) {
+	cleanups = append(cleanups, f)
+}
+
+// exitStatus is the code the process exits with once exit was called.
+var exitStatus int
+
+// exitMessage is the error the process exits with through exitWithError.
+var exitMessage string
+
+// exit runs the registered cleanups and exits with code.
+func exit(code int) {
+	exitStatus = code
+	for i := len(cleanups) - 1; i >= 0; i-- {
+		cleanups[i]()
+	}
+	os.Exit(code)
+}
+
+// exitWithError prints msg and err followed by any matching hints, then
+// exits with status 1.
+func exitWithError(msg string, err error) {
+	printError(msg, err)
+	exitMessage = msg + " " + err.Error()
+	code := int64(1)
+	printRunResult(&runResult{ExitCode: &code, Error: msg + " " + err.Error()})
+	exit(1)
+}
+
+// printError prints msg and err followed by any matching hints.
+func printError(msg string, err error) {
+	fmt.Println(msg)
+	fmt.Println(err.Error())
+	printHints(err.Error())
+}
+
+// printHints prints the remediation hints matching text, if any.
+func printHints(text string) {
+	for _, h := range hintsFor(text) {
+		fmt.Println(colorize(colorYellow, "Hint:"), h.hint)
+		fmt.Println("See:", h.doc)
+	}
+	printQuotaHints(text)
+	printSSOHint(text)
+}
+
+func hintsFor(text string) []errorHint {
+	text = strings.ToLower(text)
+	var hints []errorHint
+	for _, h := range errorHints {
+		if strings.Contains(text, strings.ToLower(h.pattern)) {
+			hints = append(hints, h)
+		}
+	}
+	return hints
+}
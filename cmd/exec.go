@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/spf13/cobra"
+)
+
+// sessionManagerPlugin is the binary that connects to SSM sessions, the
+// same one the AWS CLI uses.
+const sessionManagerPlugin = "session-manager-plugin"
+
+var enableExecuteCommand bool
+var execContainer string
+
+// execCmd runs a command in a running task through ECS Exec
+var execCmd = &cobra.Command{
+	Use:   "exec <task-arn> [-- command args...]",
+	Short: "Run a command in a running task through ECS Exec",
+	Long: `Run a command in a container of a running task through ECS Exec, like
+docker exec -it. Without a command an interactive shell (/bin/sh) is started.
+
+The task must have been launched with --enable-execute-command, its task role
+must allow the ssmmessages actions, and the Session Manager plugin for the
+AWS CLI must be installed. The task can be given as ARN, or as ID together
+with --cluster.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		command := "/bin/sh"
+		if len(args) > 1 {
+			command = shellJoin(args[1:])
+		}
+		ctx := cmd.Context()
+		cfg, cluster, task, container := findExecTarget(ctx, args[0])
+		output, err := ecs.NewFromConfig(cfg).ExecuteCommand(ctx, &ecs.ExecuteCommandInput{
+			Cluster:     aws.String(cluster),
+			Task:        task.TaskArn,
+			Container:   container.Name,
+			Command:     aws.String(command),
+			Interactive: true,
+		})
+		if err != nil {
+			exitWithError("Got error executing command:", err)
+		}
+		target := execTarget(cluster, task, container)
+		execSession := output.Session
+		err = startSession(cfg, execSession.SessionId, execSession.StreamUrl, execSession.TokenValue, map[string]interface{}{"Target": target})
+		if err != nil {
+			exitWithError("Got error running the Session Manager plugin:", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(execCmd)
+	execCmd.Flags().StringVarP(&ecsCluster, "cluster", "c", "", "Name of the Cluster, if the task is given by ID")
+	execCmd.Flags().StringVarP(&execContainer, "container", "", "", "Container to run the command in (default the first container)")
+}
+
+// findExecTarget describes a running task and picks the container given
+// with --container, or else its first container. It exits if the task
+// cannot be reached through ECS Exec.
+func findExecTarget(ctx context.Context, taskArn string) (aws.Config, string, *ecstypes.Task, *ecstypes.Container) {
+	cfg, cluster, _, task, err := findTask(ctx, taskArn)
+	if err != nil {
+		exitWithError("Got error describing task:", err)
+	}
+	if aws.ToString(task.LastStatus) != string(ecstypes.DesiredStatusRunning) {
+		fmt.Printf("Task %s is %s, ECS Exec needs a running task\n", taskID(aws.ToString(task.TaskArn)), aws.ToString(task.LastStatus))
+		os.Exit(1)
+	}
+	if !task.EnableExecuteCommand {
+		fmt.Printf("Task %s was launched without ECS Exec, launch it with --enable-execute-command\n", taskID(aws.ToString(task.TaskArn)))
+		os.Exit(1)
+	}
+	for i, c := range task.Containers {
+		if execContainer == "" || aws.ToString(c.Name) == execContainer {
+			return cfg, cluster, task, &task.Containers[i]
+		}
+	}
+	fmt.Printf("Task %s has no container named %q\n", taskID(aws.ToString(task.TaskArn)), execContainer)
+	os.Exit(1)
+	return aws.Config{}, "", nil, nil
+}
+
+// execTarget returns the SSM target of container of task.
+func execTarget(cluster string, task *ecstypes.Task, container *ecstypes.Container) string {
+	return fmt.Sprintf("ecs:%s_%s_%s", clusterName(cluster), taskID(aws.ToString(task.TaskArn)), aws.ToString(container.RuntimeId))
+}
+
+// startSession connects the terminal to an SSM session through the Session
+// Manager plugin, passing it the session and parameters the way the AWS CLI
+// does.
+func startSession(cfg aws.Config, sessionID, streamURL, tokenValue *string, parameters map[string]interface{}) error {
+	plugin, err := exec.LookPath(sessionManagerPlugin)
+	if err != nil {
+		return fmt.Errorf("%s not found, install the Session Manager plugin: https://docs.aws.amazon.com/systems-manager/latest/userguide/session-manager-working-with-install-plugin.html", sessionManagerPlugin)
+	}
+	sessionJSON, _ := json.Marshal(map[string]string{
+		"SessionId":  aws.ToString(sessionID),
+		"StreamUrl":  aws.ToString(streamURL),
+		"TokenValue": aws.ToString(tokenValue),
+	})
+	parametersJSON, _ := json.Marshal(parameters)
+	region := cfg.Region
+	endpoint := customEndpoint("ssm")
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://ssm.%s.amazonaws.com", region)
+	}
+	c := exec.Command(plugin, string(sessionJSON), region, "StartSession", os.Getenv("AWS_PROFILE"),
+		string(parametersJSON), endpoint)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return c.Run()
+}
+
+// shellJoin joins args into a command line that splits into args again,
+// quoting every argument with characters a shell would interpret.
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+func shellQuote(arg string) string {
+	if shellSafe.MatchString(arg) {
+		return arg
+	}
+	return "'" + strings.Replace(arg, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want string
+	}{
+		{"ls", "ls"},
+		{"/app/bin/run.sh", "/app/bin/run.sh"},
+		{"--since=2024-01-01T00:00:00", "--since=2024-01-01T00:00:00"},
+		{"", "''"},
+		{"a b", "'a b'"},
+		{"$HOME", "'$HOME'"},
+		{"it's", `'it'\''s'`},
+		{"a\nb", "'a\nb'"},
+		{"*.log", "'*.log'"},
+	}
+	for _, test := range tests {
+		if got := shellQuote(test.arg); got != test.want {
+			t.Errorf("shellQuote(%q) = %q, want %q", test.arg, got, test.want)
+		}
+	}
+}
+
+func TestShellJoinSplits(t *testing.T) {
+	args := []string{"sh", "-c", `echo "$1" 'x' \`, "it's", "", "a  b"}
+	got, err := splitCommand(shellJoin(args))
+	if err != nil {
+		t.Fatalf("splitCommand(shellJoin(%q)) returned error: %v", args, err)
+	}
+	if !reflect.DeepEqual(got, args) {
+		t.Errorf("splitCommand(shellJoin(%q)) = %q", args, got)
+	}
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+// Exit code policies of --exit-code-from, any other value names a container.
+const (
+	exitCodeFromEssential = "essential"
+	exitCodeFromMax       = "max"
+)
+
+var exitCodeFrom string
+
+// Synthetic exit codes of containers ECS reports no exit code for, because
+// they never started or were killed, following the conventions of docker run.
+const (
+	exitCodeNotRun = 125 // the container could not be run
+	exitCodeKilled = 137 // the container was killed with SIGKILL
+)
+
+// containerExitCode returns the exit code of container c, or a synthetic
+// exit code if it has none: exitCodeKilled if it ran out of memory and
+// exitCodeNotRun otherwise.
+func containerExitCode(c ecstypes.Container) int64 {
+	if c.ExitCode != nil {
+		return int64(*c.ExitCode)
+	}
+	if classifyStop("", aws.ToString(c.Reason)) == stopCategoryOOMKilled {
+		return exitCodeKilled
+	}
+	return exitCodeNotRun
+}
+
+// infrastructureExitCodes are the exit codes of runs that failed because
+// ECS could not run the task rather than because its program failed. They
+// sit next to exitCodeTimeout, so CI can branch on them.
+var infrastructureExitCodes = map[stopCategory]int64{
+	stopCategoryOOMKilled:         120,
+	stopCategoryPullFailure:       121,
+	stopCategorySpotInterruption:  122,
+	stopCategoryTaskFailedToStart: 123,
+}
+
+// runExitCode returns the exit code of a task that exited with exitCode
+// and stopped for category: its exit code if its program decided the
+// outcome and the infrastructure exit code otherwise.
+func runExitCode(exitCode int64, category stopCategory) int64 {
+	if code, ok := infrastructureExitCodes[category]; ok {
+		return code
+	}
+	return exitCode
+}
+
+// validateExitCodeFrom exits if --exit-code-from names a container that
+// taskDefinition does not have. It runs after the lock is taken, so it
+// exits through exit.
+func validateExitCodeFrom(taskDefinition *ecstypes.TaskDefinition) {
+	if exitCodeFrom == exitCodeFromEssential || exitCodeFrom == exitCodeFromMax {
+		return
+	}
+	if !hasContainer(taskDefinition, exitCodeFrom) {
+		fmt.Printf("Invalid --exit-code-from, expected %s, %s or a container, task definition has no container named %q\n",
+			exitCodeFromEssential, exitCodeFromMax, exitCodeFrom)
+		exit(1)
+	}
+}
+
+// taskExitCode returns the exit code of task according to policy:
+//
+//   - essential: the first essential container that failed, or else the
+//     first essential container, since an essential container exiting is
+//     what stops a task
+//   - max: the highest exit code of any container
+//   - any other value: the container of that name
+func taskExitCode(ctx context.Context, cfg aws.Config, task *ecstypes.Task, policy string) (int64, error) {
+	switch policy {
+	case exitCodeFromMax:
+		var exitCode int64
+		for _, c := range task.Containers {
+			if containerExitCode(c) > exitCode {
+				exitCode = containerExitCode(c)
+			}
+		}
+		return exitCode, nil
+	case exitCodeFromEssential, "":
+		essential := essentialContainers(describeTaskDefinition(ctx, cfg, aws.ToString(task.TaskDefinitionArn)))
+		var first *ecstypes.Container
+		for i, c := range task.Containers {
+			if !essential[aws.ToString(c.Name)] {
+				continue
+			}
+			if containerExitCode(c) != 0 {
+				return containerExitCode(c), nil
+			}
+			if first == nil {
+				first = &task.Containers[i]
+			}
+		}
+		if first == nil {
+			return 0, fmt.Errorf("task %s has no essential container", taskID(aws.ToString(task.TaskArn)))
+		}
+		return containerExitCode(*first), nil
+	default:
+		for _, c := range task.Containers {
+			if aws.ToString(c.Name) == policy {
+				return containerExitCode(c), nil
+			}
+		}
+		return 0, fmt.Errorf("task %s has no container %s", taskID(aws.ToString(task.TaskArn)), policy)
+	}
+}
+
+// essentialContainers returns the names of the essential containers of
+// taskDefinition. Containers are essential unless marked otherwise.
+func essentialContainers(taskDefinition *ecstypes.TaskDefinition) map[string]bool {
+	essential := map[string]bool{}
+	for _, c := range taskDefinition.ContainerDefinitions {
+		if c.Essential == nil || *c.Essential {
+			essential[aws.ToString(c.Name)] = true
+		}
+	}
+	return essential
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// failoverTargets are the REGION:CLUSTER[:SUBNETS[:SECURITY-GROUPS]] to
+// launch in, in order, when the launch fails for lack of capacity.
+var failoverTargets []string
+
+// failoverLaunchTargets are failoverTargets parsed by validateFailoverFlags.
+var failoverLaunchTargets []launchTarget
+
+// capacityErrors are parts of launch failures that another region or
+// cluster may not have. Patterns are matched case-insensitively.
+var capacityErrors = []string{
+	"RESOURCE:",
+	"Capacity is unavailable",
+	"InsufficientCapacity",
+	"insufficient capacity",
+	"No Container Instances were found",
+	"ServerException",
+	"ServiceUnavailable",
+}
+
+// launchTarget is where a task is launched.
+type launchTarget struct {
+	region         string
+	cluster        string
+	subnets        string
+	securityGroups string
+}
+
+// validateFailoverFlags exits if a --failover value is invalid, before
+// anything is locked or launched.
+func validateFailoverFlags() {
+	failoverLaunchTargets = nil
+	for _, spec := range failoverTargets {
+		target, ok := parseFailoverTarget(spec)
+		if !ok {
+			fmt.Printf("Invalid --failover %q, expected REGION:CLUSTER[:SUBNETS[:SECURITY-GROUPS]]\n", spec)
+			exit(1)
+		}
+		failoverLaunchTargets = append(failoverLaunchTargets, target)
+	}
+}
+
+// parseFailoverTarget parses a --failover value, reporting whether it is
+// valid.
+func parseFailoverTarget(spec string) (launchTarget, bool) {
+	parts := strings.SplitN(spec, ":", 4)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return launchTarget{}, false
+	}
+	target := launchTarget{region: parts[0], cluster: parts[1]}
+	if len(parts) > 2 {
+		target.subnets = parts[2]
+	}
+	if len(parts) > 3 {
+		target.securityGroups = parts[3]
+	}
+	return target, true
+}
+
+// isCapacityError reports whether the launch failed in a way another region
+// or cluster may not.
+func isCapacityError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, pattern := range capacityErrors {
+		if strings.Contains(msg, strings.ToLower(pattern)) {
+			return true
+		}
+	}
+	return false
+}
+
+// launchWithFailover launches the task in the cluster given by the run flags
+// and, when that fails for lack of capacity, in each of failoverTargets in
+// turn. A task definition registered from definitionFile is registered again
+// in every failover region, otherwise the latest revision of its family is
+// used there. It returns the configuration, region and cluster the task was
+// launched in, the ARNs of the tasks and the log streams of their containers,
+// or the error of the last launch.
+func launchWithFailover(ctx context.Context, cfg aws.Config, definitionFile string) (aws.Config, string, string, []string, []logStream, error) {
+	// Every attempt starts from the cluster of the run flags again.
+	targets := append([]launchTarget{{cfg.Region, ecsCluster, subnets, securityGroups}}, failoverLaunchTargets...)
+	definition := taskDefinition
+	for i, target := range targets {
+		if i > 0 {
+			cfg = newRegionConfig(ctx, target.region)
+			confirmProtectedCluster(target.cluster)
+			if definitionFile != "" {
+				definition = ParseTaskDefinition(ctx, cfg, definitionFile)
+			} else {
+				definition = taskFamily(taskDefinition)
+			}
+		}
+		if usageSummary || enableInsights {
+			ensureContainerInsights(ctx, cfg, target.cluster)
+		}
+		fmt.Printf("Launching task %s in an ECS Cluster %s...\n", definition, target.cluster)
+		taskArns, streams, err := launchTask(ctx, cfg, target, launchType, definition)
+		if err == nil {
+			return cfg, target.region, target.cluster, taskArns, streams, nil
+		}
+		if i == len(targets)-1 || !isCapacityError(err) {
+			return cfg, "", "", nil, nil, err
+		}
+		printError("Got error launching task in "+target.region+":", err)
+		fmt.Printf("Failing over to cluster %s in %s\n", targets[i+1].cluster, targets[i+1].region)
+	}
+	return cfg, "", "", nil, nil, errors.New("there is no cluster to launch the task in")
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// heartbeatInterval is how often a line is printed while waiting for tasks,
+// so CI systems that kill silent jobs do not kill the run. 0 disables it.
+var heartbeatInterval time.Duration
+
+// taskStatuses holds the last status seen of every task being waited for.
+var taskStatuses sync.Map
+
+// taskStatusSummary returns the last seen statuses of taskArns, e.g.
+// "RUNNING" or "2 RUNNING, 1 STOPPED".
+func taskStatusSummary(taskArns []string) string {
+	counts := map[string]int{}
+	for _, taskArn := range taskArns {
+		status := "PROVISIONING"
+		if s, ok := taskStatuses.Load(taskArn); ok {
+			status = s.(string)
+		}
+		counts[status]++
+	}
+	if len(taskArns) == 1 {
+		for status := range counts {
+			return status
+		}
+	}
+	var parts []string
+	for status, n := range counts {
+		parts = append(parts, fmt.Sprintf("%d %s", n, status))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ", ")
+}
+
+// startHeartbeat prints how long the run has been waiting and the status of
+// its tasks every heartbeatInterval. The returned function stops it.
+func startHeartbeat(state *runState) func() {
+	if heartbeatInterval <= 0 {
+		return func() {}
+	}
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				fmt.Printf("Still waiting after %s, task is %s\n", time.Since(state.StartedAt).Round(time.Second), taskStatusSummary(state.tasks()))
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+		<-done
+	}
+}
@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var noHistory bool
+var historyLimit int
+var historyOutput string
+
+// historyEntry records a run in the local run history.
+type historyEntry struct {
+	ID             string       `json:"id"`
+	Time           time.Time    `json:"time"`
+	Region         string       `json:"region,omitempty"`
+	Cluster        string       `json:"cluster"`
+	TaskDefinition string       `json:"taskDefinition"`
+	TaskArns       []string     `json:"taskArns,omitempty"`
+	Commands       []string     `json:"commands,omitempty"`
+	EnvNames       []string     `json:"envNames,omitempty"`
+	Duration       string       `json:"duration,omitempty"`
+	ExitCode       *int64       `json:"exitCode,omitempty"`
+	ExitReason     string       `json:"exitReason,omitempty"`
+	StopCategory   stopCategory `json:"stopCategory,omitempty"`
+}
+
+// newHistoryEntry starts the history entry of a run with the overrides
+// given on the command line. Only the names of environment variables are
+// kept, their values may be secret.
+func newHistoryEntry(cluster string, definition string) *historyEntry {
+	entry := &historyEntry{
+		ID:             runID(),
+		Time:           time.Now(),
+		Cluster:        cluster,
+		TaskDefinition: definition,
+		Commands:       commandOverrides,
+	}
+	for _, kv := range envOverrides {
+		entry.EnvNames = append(entry.EnvNames, *kv.Name)
+	}
+	for container, env := range containerEnvOverrides {
+		for _, kv := range env {
+			entry.EnvNames = append(entry.EnvNames, container+":"+*kv.Name)
+		}
+	}
+	sort.Strings(entry.EnvNames)
+	return entry
+}
+
+// historyFile returns ~/.ecs-run-task/history.jsonl.
+func historyFile() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".ecs-run-task", "history.jsonl")
+}
+
+// appendHistory appends entry as a JSON line to the history file.
+func appendHistory(entry *historyEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(historyFile()), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(historyFile(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// readHistory returns the entries of the history file, oldest first.
+// Lines that cannot be parsed are skipped.
+func readHistory() ([]historyEntry, error) {
+	f, err := os.Open(historyFile())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var entries []historyEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry historyEntry
+		if json.Unmarshal(scanner.Bytes(), &entry) == nil {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// historyCmd browses the local run history
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List earlier runs",
+	Long: `List earlier runs from the local run history in ~/.ecs-run-task/history.jsonl,
+newest first. Every run is recorded unless --no-history is passed.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if historyOutput != "text" && historyOutput != "json" {
+			fmt.Println("Unknown output format:", historyOutput)
+			os.Exit(1)
+		}
+		entries, err := readHistory()
+		if err != nil {
+			exitWithError("Got error reading history:", err)
+		}
+		var selected []historyEntry
+		for i := len(entries) - 1; i >= 0 && (historyLimit <= 0 || len(selected) < historyLimit); i-- {
+			entry := entries[i]
+			if ecsCluster != "" && clusterName(entry.Cluster) != clusterName(ecsCluster) {
+				continue
+			}
+			if taskDefinition != "" && taskFamily(entry.TaskDefinition) != taskFamily(taskDefinition) {
+				continue
+			}
+			selected = append(selected, entry)
+		}
+		if historyOutput == "json" {
+			out, _ := json.MarshalIndent(selected, "", "  ")
+			fmt.Println(string(out))
+			return
+		}
+		table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(table, "TIME\tCLUSTER\tDEFINITION\tTASK\tDURATION\tEXIT CODE\tCOMMAND")
+		for _, entry := range selected {
+			var tasks []string
+			for _, arn := range entry.TaskArns {
+				tasks = append(tasks, taskID(arn))
+			}
+			exitCode := "-"
+			if entry.ExitCode != nil {
+				exitCode = fmt.Sprint(*entry.ExitCode)
+			}
+			fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				entry.Time.Local().Format("2006-01-02 15:04:05"),
+				clusterName(entry.Cluster),
+				taskDefinitionName(entry.TaskDefinition),
+				strings.Join(tasks, ","),
+				entry.Duration,
+				exitCode,
+				strings.Join(entry.Commands, "; "))
+		}
+		table.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.Flags().StringVarP(&ecsCluster, "cluster", "c", "", "Only list runs in this cluster")
+	historyCmd.Flags().StringVarP(&taskDefinition, "task-definition", "t", "", "Only list runs of this task definition family")
+	historyCmd.Flags().IntVarP(&historyLimit, "limit", "n", 20, "List at most this many runs, 0 for all")
+	historyCmd.Flags().StringVarP(&historyOutput, "output", "o", "text", "Output format: text or json")
+}
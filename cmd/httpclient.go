@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+)
+
+var caBundle string
+
+// caBundlePEM are the certificates of --ca-bundle or AWS_CA_BUNDLE, nil to
+// trust the system roots.
+var caBundlePEM []byte
+
+// initHTTP reads the CA bundle and exits if it cannot be used.
+func initHTTP() {
+	path := caBundle
+	if path == "" {
+		path = os.Getenv("AWS_CA_BUNDLE")
+	}
+	if path == "" {
+		return
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		fmt.Println("Could not read the CA bundle:", err)
+		os.Exit(1)
+	}
+	if !x509.NewCertPool().AppendCertsFromPEM(data) {
+		fmt.Printf("The CA bundle %s contains no PEM encoded certificates\n", path)
+		os.Exit(1)
+	}
+	debugf("trusting the certificates in %s", path)
+	caBundlePEM = data
+}
+
+// newTransport returns the transport of HTTP requests outside of the AWS
+// SDK, e.g. to GitHub. It goes through the proxy of HTTPS_PROXY or
+// HTTP_PROXY unless the host is in NO_PROXY, and trusts only the CA bundle
+// if there is one, like the AWS CLI.
+func newTransport() *http.Transport {
+	t := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+	if caBundlePEM != nil {
+		pool := x509.NewCertPool()
+		pool.AppendCertsFromPEM(caBundlePEM)
+		t.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+	return t
+}
+
+// newHTTPClient returns a client using newTransport whose requests time
+// out after timeout, 0 for no timeout.
+func newHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{Transport: newTransport(), Timeout: timeout}
+}
+
+// newAWSHTTPClient returns the client of AWS API requests, which uses the
+// proxy like newTransport. The SDK only adds a CA bundle to its own client
+// type, see awsConfig.
+func newAWSHTTPClient() *awshttp.BuildableClient {
+	return awshttp.NewBuildableClient().WithTransportOptions(func(t *http.Transport) {
+		t.Proxy = http.ProxyFromEnvironment
+	})
+}
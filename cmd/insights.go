@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cwtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+const containerInsightsNamespace = "ECS/ContainerInsights"
+
+var usageSummary bool
+var enableInsights bool
+
+// containerInsightsEnabled reports whether Container Insights is enabled on
+// the cluster.
+func containerInsightsEnabled(ctx context.Context, cfg aws.Config, cluster string) (bool, error) {
+	output, err := ecs.NewFromConfig(cfg).DescribeClusters(ctx, &ecs.DescribeClustersInput{
+		Clusters: []string{cluster},
+		Include:  []ecstypes.ClusterField{ecstypes.ClusterFieldSettings},
+	})
+	if err != nil {
+		return false, err
+	}
+	for _, c := range output.Clusters {
+		for _, setting := range c.Settings {
+			if setting.Name == ecstypes.ClusterSettingNameContainerInsights {
+				value := aws.ToString(setting.Value)
+				return value == "enabled" || value == "enhanced", nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// ensureContainerInsights checks that Container Insights collects metrics
+// for the cluster before a task is launched, enabling it with
+// --enable-insights and otherwise explaining how to.
+func ensureContainerInsights(ctx context.Context, cfg aws.Config, cluster string) {
+	enabled, err := containerInsightsEnabled(ctx, cfg, cluster)
+	if err != nil {
+		printError("Got error checking Container Insights:", err)
+		return
+	}
+	if enabled {
+		return
+	}
+	if !enableInsights {
+		fmt.Printf("%s Container Insights is not enabled on cluster %s, so no utilization metrics will be collected. Pass --enable-insights to enable it.\n", colorize(colorYellow, "Hint:"), cluster)
+		return
+	}
+	_, err = ecs.NewFromConfig(cfg).UpdateClusterSettings(ctx, &ecs.UpdateClusterSettingsInput{
+		Cluster: aws.String(cluster),
+		Settings: []ecstypes.ClusterSetting{{
+			Name:  ecstypes.ClusterSettingNameContainerInsights,
+			Value: aws.String("enhanced"),
+		}},
+	})
+	if err != nil {
+		printError("Got error enabling Container Insights:", err)
+		return
+	}
+	fmt.Println("Enabled Container Insights with enhanced observability on cluster", cluster)
+}
+
+// taskMetric returns the maximum and average of a Container Insights task
+// metric over the lifetime of task. ok is false when there are no data
+// points, e.g. because task level metrics are not collected.
+func taskMetric(ctx context.Context, cfg aws.Config, task *ecstypes.Task, metric string) (max float64, avg float64, ok bool, err error) {
+	start := aws.ToTime(task.StartedAt)
+	if start.IsZero() {
+		start = aws.ToTime(task.CreatedAt)
+	}
+	end := aws.ToTime(task.StoppedAt)
+	if end.IsZero() {
+		end = time.Now()
+	}
+	output, err := cloudwatch.NewFromConfig(cfg).GetMetricStatistics(ctx, &cloudwatch.GetMetricStatisticsInput{
+		Namespace:  aws.String(containerInsightsNamespace),
+		MetricName: aws.String(metric),
+		Dimensions: []cwtypes.Dimension{
+			{Name: aws.String("ClusterName"), Value: aws.String(clusterName(aws.ToString(task.ClusterArn)))},
+			{Name: aws.String("TaskDefinitionFamily"), Value: aws.String(taskFamily(aws.ToString(task.TaskDefinitionArn)))},
+			{Name: aws.String("TaskId"), Value: aws.String(taskID(aws.ToString(task.TaskArn)))},
+		},
+		StartTime:  aws.Time(start.Add(-time.Minute)),
+		EndTime:    aws.Time(end.Add(time.Minute)),
+		Period:     aws.Int32(60),
+		Statistics: []cwtypes.Statistic{cwtypes.StatisticMaximum, cwtypes.StatisticAverage},
+	})
+	if err != nil || len(output.Datapoints) == 0 {
+		return 0, 0, false, err
+	}
+	sum := 0.0
+	for _, point := range output.Datapoints {
+		max = math.Max(max, aws.ToFloat64(point.Maximum))
+		sum += aws.ToFloat64(point.Average)
+	}
+	return max, sum / float64(len(output.Datapoints)), true, nil
+}
+
+// reportOOM explains that task ran out of memory and, with Container
+// Insights, how much memory it used at peak.
+func reportOOM(ctx context.Context, cfg aws.Config, task *ecstypes.Task) {
+	limit := aws.ToString(task.Memory)
+	for _, container := range task.Containers {
+		if strings.Contains(aws.ToString(container.Reason), "OutOfMemory") {
+			if container.Memory != nil {
+				limit = *container.Memory
+			}
+			fmt.Printf("Container %s was killed because it ran out of memory (limit %s MiB)\n", aws.ToString(container.Name), limit)
+		}
+	}
+	enabled, err := containerInsightsEnabled(ctx, cfg, aws.ToString(task.ClusterArn))
+	if err != nil || !enabled {
+		fmt.Println("Enable Container Insights on the cluster to see how much memory the task used")
+		return
+	}
+	peak, _, ok, err := taskMetric(ctx, cfg, task, "MemoryUtilized")
+	if err != nil || !ok {
+		fmt.Println("No memory metrics found for the task, task level metrics need Container Insights with enhanced observability")
+		return
+	}
+	fmt.Printf("Peak memory utilization: %.0f MiB\n", peak)
+	// Metrics are sampled per minute and can miss the spike that killed
+	// the container, so never suggest less than the current limit.
+	base := peak
+	if l, err := strconv.ParseFloat(limit, 64); err == nil {
+		base = math.Max(base, l)
+	}
+	fmt.Printf("Consider raising the memory limit to at least %.0f MiB\n", math.Ceil(base*1.25))
+}
+
+// printUsageSummary prints the average and peak CPU and memory use of task
+// next to what it reserved, to help right-size task definitions.
+func printUsageSummary(ctx context.Context, cfg aws.Config, task *ecstypes.Task) {
+	fmt.Println("Resource usage:")
+	for _, m := range []struct{ label, utilized, reserved, unit string }{
+		{"CPU", "CpuUtilized", "CpuReserved", "units"},
+		{"Memory", "MemoryUtilized", "MemoryReserved", "MiB"},
+	} {
+		peak, avg, ok, err := taskMetric(ctx, cfg, task, m.utilized)
+		if err != nil {
+			fmt.Printf("  %s: %v\n", m.label, err)
+			continue
+		}
+		if !ok {
+			fmt.Printf("  %s: no metrics yet, task level metrics need Container Insights with enhanced observability and can take a few minutes to arrive\n", m.label)
+			continue
+		}
+		reserved, _, ok, _ := taskMetric(ctx, cfg, task, m.reserved)
+		if ok && reserved > 0 {
+			fmt.Printf("  %s: avg %.0f, peak %.0f of %.0f %s (peak %.0f%%)\n", m.label, avg, peak, reserved, m.unit, peak/reserved*100)
+		} else {
+			fmt.Printf("  %s: avg %.0f, peak %.0f %s\n", m.label, avg, peak, m.unit)
+		}
+	}
+}
+
+// taskID returns the ID of a task given its ARN.
+func taskID(taskArn string) string {
+	return taskArn[strings.LastIndex(taskArn, "/")+1:]
+}
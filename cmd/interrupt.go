@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+)
+
+// interruptStopReason is the stop reason of tasks stopped because the run
+// was interrupted.
+const interruptStopReason = "ecs-run-task: interrupted"
+
+// exitCodeInterrupted is the exit code when the run is interrupted, as
+// shells report for SIGINT.
+const exitCodeInterrupted = 130
+
+var stopOnInterrupt bool
+
+// handleInterrupts decides what happens to the tasks of state when the run
+// gets SIGINT or SIGTERM until the returned function is called. With
+// --stop-on-interrupt, or if the user confirms, the tasks are stopped and
+// the run goes on to print their final status. Otherwise the tasks are left
+// running and can be resumed later. A second signal exits right away.
+func handleInterrupts(ctx context.Context, svc *ecs.Client, state *runState) func() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-done:
+			return
+		case <-signals:
+		}
+		if !stopOnInterrupt && !confirmStop("task "+taskID(state.TaskArn)) {
+			stopStatusLine()
+			fmt.Println("Leaving the task running, continue with: ecs-run-task resume", taskID(state.TaskArn))
+			result := newRunResult(state)
+			code := int64(exitCodeInterrupted)
+			result.ExitCode, result.Error = &code, "interrupted, the task is left running"
+			printRunResult(result)
+			// Locks stay held for resume, so skip the cleanups.
+			os.Exit(exitCodeInterrupted)
+		}
+		fmt.Println("Stopping the task, interrupt again to exit without waiting...")
+		stopTasks(ctx, svc, state.Cluster, state.tasks(), interruptStopReason)
+		select {
+		case <-done:
+		case <-signals:
+			exit(exitCodeInterrupted)
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
+
+// confirmStop asks whether to stop what an interrupted run is waiting for.
+// Without a terminal to ask on it is left running.
+func confirmStop(what string) bool {
+	if !isTerminal(os.Stdin) {
+		return false
+	}
+	fmt.Printf("\nStop %s? [y/N] ", what)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+var strictMode bool
+var platformVersion string
+
+// checkTaskDefinition returns deprecation and best practice warnings for
+// running taskDefinition.
+func checkTaskDefinition(taskDefinition *ecstypes.TaskDefinition) []string {
+	var warnings []string
+	if taskDefinition.Status == ecstypes.TaskDefinitionStatusInactive {
+		warnings = append(warnings, fmt.Sprintf("task definition %s:%d is deregistered", aws.ToString(taskDefinition.Family), taskDefinition.Revision))
+	}
+	if isDeprecatedPlatformVersion(platformVersion) {
+		warnings = append(warnings, fmt.Sprintf("platform version %s is deprecated, use 1.4.0 or LATEST", platformVersion))
+	}
+	for _, container := range taskDefinition.ContainerDefinitions {
+		name := aws.ToString(container.Name)
+		if tag := imageTag(aws.ToString(container.Image)); tag == "latest" {
+			warnings = append(warnings, fmt.Sprintf("container %s uses the latest tag, pin a version or digest so runs are reproducible", name))
+		}
+		if aws.ToBool(container.Essential) && container.HealthCheck == nil && len(taskDefinition.ContainerDefinitions) > 1 {
+			warnings = append(warnings, fmt.Sprintf("essential container %s has no health check", name))
+		}
+		if len(container.Links) > 0 {
+			warnings = append(warnings, fmt.Sprintf("container %s uses links, a legacy feature that is not supported with awsvpc networking", name))
+		}
+	}
+	if len(taskDefinition.InferenceAccelerators) > 0 {
+		warnings = append(warnings, "Elastic Inference accelerators are deprecated")
+	}
+	return warnings
+}
+
+// lintTaskDefinition prints the warnings for taskDefinition and exits in
+// strict mode if there are any.
+func lintTaskDefinition(taskDefinition *ecstypes.TaskDefinition) {
+	warnings := checkTaskDefinition(taskDefinition)
+	for _, warning := range warnings {
+		warnf("%s", warning)
+	}
+	if strictMode && len(warnings) > 0 {
+		fmt.Println("Refusing to run a task definition with warnings in --strict mode")
+		exit(1)
+	}
+}
+
+// imageTag returns the tag of an image reference, "latest" when it has
+// neither a tag nor a digest.
+func imageTag(image string) string {
+	if strings.Contains(image, "@") {
+		return ""
+	}
+	name := image[strings.LastIndex(image, "/")+1:]
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		return name[i+1:]
+	}
+	return "latest"
+}
+
+// isDeprecatedPlatformVersion reports whether version is a Fargate Linux
+// platform version older than 1.4.0.
+func isDeprecatedPlatformVersion(version string) bool {
+	parts := strings.Split(version, ".")
+	if len(parts) != 3 {
+		return false
+	}
+	major, err1 := strconv.Atoi(parts[0])
+	minor, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	return major < 1 || (major == 1 && minor < 4)
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import "testing"
+
+func TestImageTag(t *testing.T) {
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{"nginx", "latest"},
+		{"nginx:1.25", "1.25"},
+		{"nginx:latest", "latest"},
+		{"registry.example.com:5000/team/app", "latest"},
+		{"registry.example.com:5000/team/app:v1", "v1"},
+		{"123456789012.dkr.ecr.eu-west-1.amazonaws.com/app@sha256:0123abcd", ""},
+		{"app:v1@sha256:0123abcd", ""},
+	}
+	for _, test := range tests {
+		if got := imageTag(test.image); got != test.want {
+			t.Errorf("imageTag(%q) = %q, want %q", test.image, got, test.want)
+		}
+	}
+}
+
+func TestIsDeprecatedPlatformVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		want    bool
+	}{
+		{"LATEST", false},
+		{"", false},
+		{"1.4.0", false},
+		{"1.10.0", false},
+		{"2.0.0", false},
+		{"1.3.0", true},
+		{"1.0.0", true},
+		{"0.9.0", true},
+		{"1.3", false},
+		{"1.x.0", false},
+	}
+	for _, test := range tests {
+		if got := isDeprecatedPlatformVersion(test.version); got != test.want {
+			t.Errorf("isDeprecatedPlatformVersion(%q) = %t, want %t", test.version, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	logstypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+// Limits of a single Live Tail session.
+const (
+	maxLiveTailGroups  = 10
+	maxLiveTailStreams = 100
+)
+
+var useLiveTail bool
+
+// liveStream is a log stream followed with Live Tail. last is the timestamp
+// of the newest event printed so far in milliseconds of unix time.
+type liveStream struct {
+	stream  logStream
+	printer *logPrinter
+	last    int64
+}
+
+// catchUp prints the events of the stream after the last one printed and
+// before until, the events a Live Tail session started at until does not
+// deliver.
+func (l *liveStream) catchUp(ctx context.Context, svc *cloudwatchlogs.Client, until int64) error {
+	input := logEventsInput(l.stream)
+	if l.last > 0 {
+		input.StartTime, input.StartFromHead, input.Limit = aws.Int64(l.last+1), aws.Bool(true), nil
+	}
+	input.EndTime = aws.Int64(until)
+	for {
+		resp, err := svc.GetLogEvents(ctx, input)
+		if err != nil {
+			if isNotFound(err) {
+				return nil
+			}
+			return err
+		}
+		l.print(resp.Events)
+		// The forward token stays the same once the end of the stream is reached.
+		if resp.NextForwardToken == nil || aws.ToString(resp.NextForwardToken) == aws.ToString(input.NextToken) {
+			return nil
+		}
+		nextLogEvents(input, resp.NextForwardToken)
+	}
+}
+
+func (l *liveStream) print(events []logstypes.OutputLogEvent) {
+	l.printer.print(events)
+	for _, event := range events {
+		if timestamp := aws.ToInt64(event.Timestamp); timestamp > l.last {
+			l.last = timestamp
+		}
+	}
+}
+
+// followLive prints new events of several log streams as they arrive until
+// stop is closed, like follow, but has CloudWatch push them through Live
+// Tail sessions instead of polling. It returns false without printing
+// anything if Live Tail cannot be used, e.g. because it is not allowed or
+// the log groups do not exist yet, so the caller can poll instead.
+func followLive(ctx context.Context, cfg aws.Config, streams []logStream, stop <-chan struct{}) bool {
+	svc := cloudwatchlogs.NewFromConfig(cfg)
+	input, err := liveTailInput(ctx, svc, streams)
+	if err != nil {
+		warnf("cannot use Live Tail, polling for logs instead: %s", err)
+		return false
+	}
+	out := &syncWriter{w: os.Stdout}
+	live := map[string]*liveStream{}
+	for _, s := range streams {
+		live[s.Stream] = &liveStream{stream: s, printer: newLogPrinter(out, s)}
+	}
+	defer func() {
+		for _, l := range live {
+			l.printer.flush()
+		}
+	}()
+	started, sampled := false, false
+	for {
+		// AWS takes milliseconds of unix time.
+		start := time.Now().UnixNano() / int64(time.Millisecond)
+		resp, err := svc.StartLiveTail(ctx, input)
+		if err != nil && !started {
+			warnf("cannot use Live Tail, polling for logs instead: %s", err)
+			return false
+		}
+		if err != nil {
+			printError("Error starting Live Tail, polling for logs instead:", err)
+			pollLive(ctx, svc, live, stop)
+			return true
+		}
+		started = true
+		debugf("started Live Tail session for %d log streams", len(streams))
+		events := resp.GetStream()
+		for _, l := range live {
+			if err := l.catchUp(ctx, svc, start); err != nil {
+				printError("Error getting log events:", err)
+			}
+		}
+		stopped := false
+		for receiving := true; receiving && !stopped; {
+			select {
+			case <-stop:
+				stopped = true
+			case event, ok := <-events.Events():
+				update, isUpdate := event.(*logstypes.StartLiveTailResponseStreamMemberSessionUpdate)
+				if !ok {
+					receiving = false
+					break
+				}
+				if !isUpdate {
+					continue
+				}
+				if update.Value.SessionMetadata != nil && update.Value.SessionMetadata.Sampled && !sampled {
+					warnf("the task logs more than Live Tail delivers, some lines are left out. Run without --live-tail to print all of them.")
+					sampled = true
+				}
+				for _, e := range update.Value.SessionResults {
+					if l := live[aws.ToString(e.LogStreamName)]; l != nil {
+						l.print([]logstypes.OutputLogEvent{{
+							Message:       e.Message,
+							Timestamp:     e.Timestamp,
+							IngestionTime: e.IngestionTime,
+						}})
+					}
+				}
+			}
+		}
+		events.Close()
+		if stopped {
+			// Print what arrived since the last update, as follow does.
+			pollLive(ctx, svc, live, closedChannel())
+			return true
+		}
+		if err := events.Err(); err != nil {
+			printError("Live Tail session failed, polling for logs instead:", err)
+			pollLive(ctx, svc, live, stop)
+			return true
+		}
+		// Sessions end after a few hours, continue with a new one.
+		debugf("Live Tail session ended")
+	}
+}
+
+// pollLive prints new events of the streams by polling until stop is
+// closed, then prints the events that arrived since the last poll.
+func pollLive(ctx context.Context, svc *cloudwatchlogs.Client, live map[string]*liveStream, stop <-chan struct{}) {
+	stopped := false
+	for {
+		// AWS takes milliseconds of unix time.
+		now := time.Now().UnixNano() / int64(time.Millisecond)
+		for _, l := range live {
+			if err := l.catchUp(ctx, svc, now); err != nil {
+				printError("Error getting log events:", err)
+			}
+		}
+		if stopped {
+			return
+		}
+		select {
+		case <-stop:
+			stopped = true
+		case <-time.After(pollDelay(0)):
+		}
+	}
+}
+
+// liveTailInput returns the input of a Live Tail session of streams. Live
+// Tail takes log groups by ARN.
+func liveTailInput(ctx context.Context, svc *cloudwatchlogs.Client, streams []logStream) (*cloudwatchlogs.StartLiveTailInput, error) {
+	if len(streams) > maxLiveTailStreams {
+		return nil, fmt.Errorf("%d log streams, at most %d can be followed", len(streams), maxLiveTailStreams)
+	}
+	input := &cloudwatchlogs.StartLiveTailInput{}
+	seen := map[string]bool{}
+	for _, s := range streams {
+		input.LogStreamNames = append(input.LogStreamNames, s.Stream)
+		if seen[s.Group] {
+			continue
+		}
+		seen[s.Group] = true
+		arn, err := logGroupArn(ctx, svc, s.Group)
+		if err != nil {
+			return nil, err
+		}
+		input.LogGroupIdentifiers = append(input.LogGroupIdentifiers, arn)
+	}
+	if len(input.LogGroupIdentifiers) > maxLiveTailGroups {
+		return nil, fmt.Errorf("%d log groups, at most %d can be followed", len(input.LogGroupIdentifiers), maxLiveTailGroups)
+	}
+	return input, nil
+}
+
+// logGroupArn returns the ARN of the log group named name.
+func logGroupArn(ctx context.Context, svc *cloudwatchlogs.Client, name string) (string, error) {
+	paginator := cloudwatchlogs.NewDescribeLogGroupsPaginator(svc, &cloudwatchlogs.DescribeLogGroupsInput{
+		LogGroupNamePrefix: aws.String(name),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return "", err
+		}
+		for _, group := range page.LogGroups {
+			if aws.ToString(group.LogGroupName) == name {
+				// The ARN of DescribeLogGroups ends in :* for all its streams.
+				return strings.TrimSuffix(aws.ToString(group.Arn), ":*"), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("log group %s does not exist", name)
+}
+
+// closedChannel returns a channel that is already closed.
+func closedChannel() <-chan struct{} {
+	c := make(chan struct{})
+	close(c)
+	return c
+}
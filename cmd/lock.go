@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+var lockTable string
+
+// acquireLock takes the lock named key in the DynamoDB table, which must
+// have a string partition key named LockID. The lock expires after ttl so a
+// run that crashed cannot hold it forever.
+func acquireLock(ctx context.Context, cfg aws.Config, table string, key string, ttl time.Duration) error {
+	now := time.Now()
+	_, err := dynamodb.NewFromConfig(cfg).PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(table),
+		Item: map[string]ddbtypes.AttributeValue{
+			"LockID":    &ddbtypes.AttributeValueMemberS{Value: key},
+			"Owner":     &ddbtypes.AttributeValueMemberS{Value: lockOwner()},
+			"ExpiresAt": &ddbtypes.AttributeValueMemberN{Value: strconv.FormatInt(now.Add(ttl).Unix(), 10)},
+		},
+		ConditionExpression: aws.String("attribute_not_exists(LockID) OR ExpiresAt < :now"),
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":now": &ddbtypes.AttributeValueMemberN{Value: strconv.FormatInt(now.Unix(), 10)},
+		},
+	})
+	var conditionFailed *ddbtypes.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return fmt.Errorf("lock %s is held by %s", key, lockHolder(ctx, cfg, table, key))
+	}
+	return err
+}
+
+// releaseLockOnExit releases the lock named key held by owner when the
+// process exits.
+func releaseLockOnExit(ctx context.Context, cfg aws.Config, table string, key string, owner string) {
+	onExit(func() {
+		if err := releaseLock(ctx, cfg, table, key, owner); err != nil {
+			printError("Got error releasing lock:", err)
+		}
+	})
+}
+
+// releaseLock releases the lock named key if it is still held by owner.
+func releaseLock(ctx context.Context, cfg aws.Config, table string, key string, owner string) error {
+	_, err := dynamodb.NewFromConfig(cfg).DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(table),
+		Key: map[string]ddbtypes.AttributeValue{
+			"LockID": &ddbtypes.AttributeValueMemberS{Value: key},
+		},
+		ConditionExpression: aws.String("#owner = :owner"),
+		ExpressionAttributeNames: map[string]string{
+			"#owner": "Owner",
+		},
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":owner": &ddbtypes.AttributeValueMemberS{Value: owner},
+		},
+	})
+	return err
+}
+
+func lockHolder(ctx context.Context, cfg aws.Config, table string, key string) string {
+	output, err := dynamodb.NewFromConfig(cfg).GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(table),
+		Key: map[string]ddbtypes.AttributeValue{
+			"LockID": &ddbtypes.AttributeValueMemberS{Value: key},
+		},
+	})
+	if err != nil {
+		return "another run"
+	}
+	owner, ok1 := output.Item["Owner"].(*ddbtypes.AttributeValueMemberS)
+	expires, ok2 := output.Item["ExpiresAt"].(*ddbtypes.AttributeValueMemberN)
+	if !ok1 || !ok2 {
+		return "another run"
+	}
+	expiresAt, _ := strconv.ParseInt(expires.Value, 10, 64)
+	return fmt.Sprintf("%s until %s", owner.Value, time.Unix(expiresAt, 0).Format(time.RFC3339))
+}
+
+// lockKey returns the name of the lock for running taskDefinition in cluster.
+func lockKey(cluster string, taskDefinition string) string {
+	return clusterName(cluster) + "/" + taskFamily(taskDefinition)
+}
+
+// taskFamily returns the family of a task definition given as family,
+// family:revision or ARN.
+func taskFamily(taskDefinition string) string {
+	family := taskDefinition[strings.LastIndex(taskDefinition, "/")+1:]
+	if i := strings.Index(family, ":"); i >= 0 {
+		family = family[:i]
+	}
+	return family
+}
+
+func lockOwner() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s@%s:%d", currentUser(), host, os.Getpid())
+}
+
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
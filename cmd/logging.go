@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go/logging"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// debug enables debug messages, including a line per AWS API request.
+var debug bool
+
+// initLogging applies --debug once the flags are parsed. Debugging implies
+// --verbose.
+func initLogging() {
+	if debug {
+		verbose = true
+	}
+}
+
+// infof prints an informational message.
+func infof(format string, args ...interface{}) {
+	fmt.Printf(format+"\n", args...)
+}
+
+// warnf prints a warning.
+func warnf(format string, args ...interface{}) {
+	fmt.Println(colorize(colorYellow, "Warning:"), fmt.Sprintf(format, args...))
+}
+
+// debugf prints a debug message with a timestamp, if --debug was given.
+func debugf(format string, args ...interface{}) {
+	if !debug {
+		return
+	}
+	fmt.Println(colorize(colorBlue, "Debug:"), time.Now().Format("15:04:05.000"), fmt.Sprintf(format, args...))
+}
+
+// debugConfig makes the clients of cfg log retries, errors and a line per
+// request with its request ID, if --debug was given. Request and response
+// bodies are not logged, they may contain secrets.
+func debugConfig(cfg *aws.Config) {
+	if !debug {
+		return
+	}
+	cfg.ClientLogMode = aws.LogRetries
+	cfg.Logger = logging.LoggerFunc(func(classification logging.Classification, format string, v ...interface{}) {
+		debugf(format, v...)
+	})
+	cfg.APIOptions = append(cfg.APIOptions, func(stack *middleware.Stack) error {
+		return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("DebugRequest", debugRequest), middleware.After)
+	})
+}
+
+// debugRequest logs a request once it completed, after all its retries.
+func debugRequest(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+	started := time.Now()
+	out, metadata, err := next.HandleInitialize(ctx, in)
+	status := 0
+	if resp, ok := awsmiddleware.GetRawResponse(metadata).(*smithyhttp.Response); ok {
+		status = resp.StatusCode
+	}
+	var respErr *awshttp.ResponseError
+	if errors.As(err, &respErr) {
+		status = respErr.HTTPStatusCode()
+	}
+	requestID, _ := awsmiddleware.GetRequestIDMetadata(metadata)
+	retries := 0
+	if results, ok := retry.GetAttemptResults(metadata); ok && len(results.Results) > 0 {
+		retries = len(results.Results) - 1
+	}
+	msg := fmt.Sprintf("%s %s in %s: HTTP %d, request ID %s, %d retries, %s",
+		awsmiddleware.GetServiceID(ctx), awsmiddleware.GetOperationName(ctx), awsmiddleware.GetRegion(ctx),
+		status, requestID, retries, time.Since(started).Round(time.Millisecond))
+	if err != nil {
+		msg += ", error: " + err.Error()
+	}
+	debugf("%s", msg)
+	return out, metadata, err
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	logstypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+// retentionDays are the retention periods CloudWatch Logs accepts.
+var retentionDays = []int64{1, 3, 5, 7, 14, 30, 60, 90, 120, 150, 180, 365, 400, 545, 731, 1096, 1827, 2192, 2557, 2922, 3288, 3653}
+
+var createLogGroup bool
+var logRetentionDays int64
+
+// validateLogGroupFlags exits if --log-retention-days is not a retention
+// period CloudWatch Logs accepts or is used without --create-log-group.
+func validateLogGroupFlags() {
+	if logRetentionDays == 0 {
+		return
+	}
+	if !createLogGroup {
+		fmt.Println("--log-retention-days needs --create-log-group")
+		exit(1)
+	}
+	for _, days := range retentionDays {
+		if days == logRetentionDays {
+			return
+		}
+	}
+	fmt.Printf("Invalid --log-retention-days %d, expected one of %s\n", logRetentionDays, strings.Trim(fmt.Sprint(retentionDays), "[]"))
+	exit(1)
+}
+
+// createLogGroups creates the awslogs log groups of td that do not exist
+// yet, with the retention of --log-retention-days. Existing groups are left
+// alone.
+func createLogGroups(ctx context.Context, cfg aws.Config, td *ecstypes.TaskDefinition) {
+	seen := map[string]bool{}
+	for _, container := range td.ContainerDefinitions {
+		logConfig := container.LogConfiguration
+		if logConfig == nil || logConfig.LogDriver != ecstypes.LogDriverAwslogs {
+			continue
+		}
+		group := logConfig.Options["awslogs-group"]
+		region := logConfig.Options["awslogs-region"]
+		if group == "" || seen[region+"/"+group] {
+			continue
+		}
+		seen[region+"/"+group] = true
+		groupCfg := cfg
+		if region != "" && region != cfg.Region {
+			groupCfg = newRegionConfig(ctx, region)
+		}
+		if err := ensureLogGroup(ctx, groupCfg, group); err != nil {
+			exitWithError("Got error creating log group "+group+":", err)
+		}
+	}
+}
+
+// ensureLogGroup creates the log group name unless it exists.
+func ensureLogGroup(ctx context.Context, cfg aws.Config, name string) error {
+	svc := cloudwatchlogs.NewFromConfig(cfg)
+	_, err := svc.CreateLogGroup(ctx, &cloudwatchlogs.CreateLogGroupInput{LogGroupName: aws.String(name)})
+	var exists *logstypes.ResourceAlreadyExistsException
+	if errors.As(err, &exists) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println("Created log group", name)
+	if logRetentionDays == 0 {
+		return nil
+	}
+	_, err = svc.PutRetentionPolicy(ctx, &cloudwatchlogs.PutRetentionPolicyInput{
+		LogGroupName:    aws.String(name),
+		RetentionInDays: aws.Int32(int32(logRetentionDays)),
+	})
+	return err
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sync/atomic"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+)
+
+// logPatternStopReason is the stop reason of tasks stopped because a log
+// line matched --fail-on-log-pattern, and the exit reason of runs failed
+// for it.
+const logPatternStopReason = "ecs-run-task: log line matched --fail-on-log-pattern"
+
+var failOnLogPattern string
+var stopOnLogPattern bool
+
+// failPattern is the compiled --fail-on-log-pattern of the current run and
+// logPatternMatched is set to 1 once a log line matched it.
+var failPattern *regexp.Regexp
+var logPatternMatched int32
+
+// onLogPatternMatch, if set, is called when the first log line matched
+// failPattern.
+var onLogPatternMatch func()
+
+// validateLogPatternFlags exits if --fail-on-log-pattern is not a valid
+// regular expression or --stop-on-log-pattern is used without it.
+func validateLogPatternFlags() {
+	if _, err := regexp.Compile(failOnLogPattern); err != nil {
+		fmt.Printf("Invalid --fail-on-log-pattern %q: %s\n", failOnLogPattern, err)
+		exit(1)
+	}
+	if stopOnLogPattern && (failOnLogPattern == "" || !followLogs) {
+		fmt.Println("--stop-on-log-pattern needs --fail-on-log-pattern and --follow")
+		exit(1)
+	}
+}
+
+// watchLogPattern starts checking the log lines of the run of state against
+// its --fail-on-log-pattern, stopping its tasks on the first match if
+// requested.
+func watchLogPattern(ctx context.Context, cfg aws.Config, state *runState) {
+	failPattern, onLogPatternMatch = nil, nil
+	atomic.StoreInt32(&logPatternMatched, 0)
+	if state.FailOnLogPattern == "" {
+		return
+	}
+	failPattern = regexp.MustCompile(state.FailOnLogPattern)
+	if state.StopOnLogPattern {
+		onLogPatternMatch = func() {
+			fmt.Println("Stopping the task...")
+			stopTasks(ctx, ecs.NewFromConfig(cfg), state.Cluster, state.tasks(), logPatternStopReason)
+		}
+	}
+}
+
+// checkLogPattern records whether a log line matches failPattern. Every log
+// line is checked, including those --grep and --grep-v hide.
+func checkLogPattern(message string) {
+	if failPattern == nil || !failPattern.MatchString(message) {
+		return
+	}
+	if !atomic.CompareAndSwapInt32(&logPatternMatched, 0, 1) {
+		return
+	}
+	fmt.Printf("Log line matched --fail-on-log-pattern %q, the run fails\n", failPattern.String())
+	if onLogPatternMatch != nil {
+		go onLogPatternMatch()
+	}
+}
+
+// logPatternExit fails a run that exited with exitCode and exitReason if a
+// log line matched --fail-on-log-pattern.
+func logPatternExit(exitCode int64, exitReason string) (int64, string) {
+	if atomic.LoadInt32(&logPatternMatched) == 0 || exitCode != 0 {
+		return exitCode, exitReason
+	}
+	return 1, logPatternStopReason
+}
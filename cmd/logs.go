@@ -0,0 +1,570 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	logstypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/spf13/pflag"
+)
+
+const (
+	logTimeEvent     = "event"
+	logTimeIngestion = "ingestion"
+)
+
+var logTime string
+
+const (
+	timestampsLocal   = "local"
+	timestampsRFC3339 = "rfc3339"
+	timestampsUnix    = "unix"
+	timestampsNone    = "none"
+)
+
+var logTimestamps string
+
+// validateLogFlags exits if a log flag has an invalid value.
+func validateLogFlags() {
+	if logTime != logTimeEvent && logTime != logTimeIngestion {
+		fmt.Printf("Invalid --log-time %q, expected %s or %s\n", logTime, logTimeEvent, logTimeIngestion)
+		exit(1)
+	}
+	if logsSince < 0 {
+		fmt.Println("--since must not be negative")
+		exit(1)
+	}
+	if logTail < 0 || logTail > maxTailEvents {
+		fmt.Printf("--tail must be between 0 and %d\n", maxTailEvents)
+		exit(1)
+	}
+	if filterMode() && (logTail > 0 || useLiveTail) {
+		fmt.Println("--filter-pattern and --filter-logs cannot be combined with --tail or --live-tail")
+		exit(1)
+	}
+	var err error
+	if logGrep != "" {
+		if grepPattern, err = regexp.Compile(logGrep); err != nil {
+			fmt.Printf("Invalid --grep %q: %s\n", logGrep, err)
+			exit(1)
+		}
+	}
+	if logGrepV != "" {
+		if grepVPattern, err = regexp.Compile(logGrepV); err != nil {
+			fmt.Printf("Invalid --grep-v %q: %s\n", logGrepV, err)
+			exit(1)
+		}
+	}
+	compileMasks()
+	switch logTimestamps {
+	case timestampsLocal, timestampsRFC3339, timestampsUnix, timestampsNone:
+	default:
+		fmt.Printf("Invalid --timestamps %q, expected %s, %s, %s or %s\n", logTimestamps, timestampsLocal, timestampsRFC3339, timestampsUnix, timestampsNone)
+		exit(1)
+	}
+}
+
+// matchesGrep reports whether a log line passes --grep and --grep-v.
+func matchesGrep(message string) bool {
+	if grepPattern != nil && !grepPattern.MatchString(message) {
+		return false
+	}
+	return grepVPattern == nil || !grepVPattern.MatchString(message)
+}
+
+// addTimestampsFlag adds --timestamps to flags.
+func addTimestampsFlag(flags *pflag.FlagSet) {
+	flags.StringVarP(&logTimestamps, "timestamps", "", timestampsLocal, "Format of the timestamp in front of log lines: local, rfc3339 (UTC with milliseconds), unix (seconds with milliseconds) or none")
+}
+
+// formatTimestamp formats the time of a log line as selected with
+// --timestamps, or returns "" if lines are printed without one.
+func formatTimestamp(t time.Time) string {
+	switch logTimestamps {
+	case timestampsNone:
+		return ""
+	case timestampsRFC3339:
+		return t.UTC().Format("2006-01-02T15:04:05.000Z07:00")
+	case timestampsUnix:
+		return fmt.Sprintf("%d.%03d", t.Unix(), t.Nanosecond()/int(time.Millisecond))
+	}
+	return t.Truncate(time.Second).String()
+}
+
+// eventTime returns the time of event selected with --log-time. Ingestion
+// time is set by CloudWatch and so is immune to clock skew in containers.
+func eventTime(event logstypes.OutputLogEvent) time.Time {
+	// AWS returns milliseconds of unix time.
+	millis := aws.ToInt64(event.Timestamp)
+	if logTime == logTimeIngestion {
+		millis = aws.ToInt64(event.IngestionTime)
+	}
+	return time.Unix(millis/1000, millis%1000*int64(time.Millisecond))
+}
+
+// logStream is a CloudWatch log stream to print, with the label its lines
+// are prefixed with when several streams are printed.
+type logStream struct {
+	Group  string `json:"group"`
+	Stream string `json:"stream"`
+	Label  string `json:"label,omitempty"`
+}
+
+var logConcurrency int
+var followLogs bool
+var maxLogEvents int
+
+// logsSince and logTail limit which events of a stream are printed: those
+// of the last logsSince and the last logTail, 0 for no limit.
+var logsSince time.Duration
+var logTail int
+
+// logGrep and logGrepV are regular expressions log lines must and must not
+// match to be printed, compiled into grepPattern and grepVPattern.
+var logGrep string
+var logGrepV string
+var grepPattern *regexp.Regexp
+var grepVPattern *regexp.Regexp
+
+// logFilterPattern is a CloudWatch Logs filter pattern events are filtered
+// with before they are fetched.
+var logFilterPattern string
+
+// filterLogs fetches the events of every log stream whose name starts with
+// the name of a stream, e.g. those a log router writes next to it.
+var filterLogs bool
+
+// filterMode reports whether events are fetched with FilterLogEvents
+// rather than read stream by stream with GetLogEvents.
+func filterMode() bool {
+	return logFilterPattern != "" || filterLogs
+}
+
+// logFile is the path --log-file writes log lines to, logFileOutput the
+// file once opened. With logFilePrefixes lines are written with the
+// timestamp and prefix they are printed with.
+var logFile string
+var logFilePrefixes bool
+var logFileOutput io.Writer
+
+// openLogFile creates the --log-file, exiting if that fails.
+func openLogFile() {
+	if logFile == "" {
+		return
+	}
+	f, err := os.Create(logFile)
+	if err != nil {
+		exitWithError("Got error creating --log-file:", err)
+	}
+	onExit(func() { f.Close() })
+	logFileOutput = &syncWriter{w: f}
+}
+
+// logStreamWait is how long to wait for a log stream that does not exist
+// yet, as happens right after a task started.
+var logStreamWait time.Duration
+
+// isNotFound reports whether err says that a log stream or group does not
+// exist.
+func isNotFound(err error) bool {
+	var notFound *logstypes.ResourceNotFoundException
+	return errors.As(err, &notFound)
+}
+
+// waitForLogStream calls fetch until it does not fail because the log
+// stream s does not exist, backing off between attempts, for at most
+// logStreamWait. It returns the last error of fetch.
+func waitForLogStream(s logStream, fetch func() error) error {
+	deadline := time.Now().Add(logStreamWait)
+	for attempt := 0; ; attempt++ {
+		err := fetch()
+		if !isNotFound(err) || time.Now().After(deadline) {
+			return err
+		}
+		delay := pollDelay(attempt)
+		if verbose {
+			fmt.Printf("Log stream %s does not exist yet, next check in %s\n", s.Stream, delay.Round(time.Second))
+		}
+		time.Sleep(delay)
+	}
+}
+
+// maxTailEvents is the most events a single GetLogEvents call returns.
+const maxTailEvents = 10000
+
+// logEventsInput returns the input of the first GetLogEvents call for a
+// stream, starting at the events selected with --since and --tail. Later
+// calls continue with nextLogEvents.
+func logEventsInput(s logStream) *cloudwatchlogs.GetLogEventsInput {
+	input := &cloudwatchlogs.GetLogEventsInput{
+		LogGroupName:  aws.String(s.Group),
+		LogStreamName: aws.String(s.Stream),
+		StartFromHead: aws.Bool(true),
+	}
+	if logsSince > 0 {
+		// AWS takes milliseconds of unix time.
+		input.StartTime = aws.Int64(time.Now().Add(-logsSince).UnixNano() / int64(time.Millisecond))
+	}
+	if logTail > 0 {
+		// Without a token and reading backwards the newest events are returned.
+		input.StartFromHead = aws.Bool(false)
+		input.Limit = aws.Int32(int32(logTail))
+	}
+	return input
+}
+
+// nextLogEvents makes input fetch the events after the forward token of
+// the previous call. Forward tokens are read from the head on, also after a
+// first call reading backwards for --tail.
+func nextLogEvents(input *cloudwatchlogs.GetLogEventsInput, token *string) {
+	input.NextToken, input.Limit, input.StartFromHead = token, nil, aws.Bool(true)
+}
+
+// printLogs streams the events of a log stream to stdout.
+func printLogs(ctx context.Context, cfg aws.Config, logStreamName string, logGroupName string) {
+	printLogStreams(ctx, cfg, []logStream{{Group: logGroupName, Stream: logStreamName}})
+}
+
+// printLogStreams streams the events of several log streams to stdout. At
+// most logConcurrency streams are fetched at a time, each paging through its
+// own stream independently.
+func printLogStreams(ctx context.Context, cfg aws.Config, streams []logStream) {
+	out := &syncWriter{w: os.Stdout}
+	workers := make(chan struct{}, maxInt(logConcurrency, 1))
+	var wg sync.WaitGroup
+	for _, s := range streams {
+		wg.Add(1)
+		workers <- struct{}{}
+		go func(s logStream) {
+			defer wg.Done()
+			defer func() { <-workers }()
+			printer := newLogPrinter(out, s)
+			GetLogs(ctx, cfg, s.Stream, s.Group, printer.print)
+			printer.flush()
+		}(s)
+	}
+	wg.Wait()
+}
+
+// syncWriter serializes writes from concurrent log printers, so every page
+// they write stays in one piece.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
+
+// logPrinter writes log events as they arrive, rejoining split lines on the
+// way. Only a page and a partially assembled line are held in memory.
+type logPrinter struct {
+	out       io.Writer
+	stream    logStream
+	assembler eventAssembler
+}
+
+func newLogPrinter(w io.Writer, s logStream) *logPrinter {
+	return &logPrinter{out: w, stream: s}
+}
+
+// print writes a page of events.
+func (p *logPrinter) print(events []logstypes.OutputLogEvent) {
+	sortEvents(events)
+	var page bytes.Buffer
+	for _, event := range events {
+		for _, complete := range p.assembler.add(event) {
+			p.write(&page, complete)
+		}
+	}
+	p.out.Write(page.Bytes())
+}
+
+// flush writes the last partial line, if any.
+func (p *logPrinter) flush() {
+	var page bytes.Buffer
+	for _, event := range p.assembler.flush() {
+		p.write(&page, event)
+	}
+	p.out.Write(page.Bytes())
+}
+
+func (p *logPrinter) write(w io.Writer, event logstypes.OutputLogEvent) {
+	checkLogPattern(aws.ToString(event.Message))
+	message := maskSecrets(aws.ToString(event.Message))
+	if !matchesGrep(message) {
+		return
+	}
+	emitProgress(progressEvent{
+		Event:     progressLogLine,
+		Time:      eventTime(event),
+		LogGroup:  p.stream.Group,
+		LogStream: p.stream.Stream,
+		Message:   aws.String(message),
+	})
+	var prefix string
+	if timestamp := formatTimestamp(eventTime(event)); timestamp != "" {
+		prefix = "[" + timestamp + "] "
+	}
+	if p.stream.Label != "" {
+		fmt.Fprintf(w, "%s%s | %s\n", prefix, colorize(labelColor(p.stream.Label), p.stream.Label), message)
+		prefix += p.stream.Label + " | "
+	} else {
+		fmt.Fprintf(w, "%s%s\n", prefix, message)
+	}
+	if logFileOutput != nil {
+		if !logFilePrefixes {
+			prefix = ""
+		}
+		fmt.Fprintf(logFileOutput, "%s%s\n", prefix, message)
+	}
+}
+
+func maxInt(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// sortEvents orders events by the time selected with --log-time. Events are
+// returned in producer timestamp order, so only ingestion time needs sorting.
+// Since logs are streamed, this orders the events within a page.
+func sortEvents(events []logstypes.OutputLogEvent) {
+	if logTime != logTimeIngestion {
+		return
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return aws.ToInt64(events[i].IngestionTime) < aws.ToInt64(events[j].IngestionTime)
+	})
+}
+
+// splitMessageSizes are the sizes at which long lines end up split into
+// several log events: Docker splits lines into 16 KiB chunks and the awslogs
+// driver splits at the CloudWatch event size limit minus 26 bytes overhead.
+var splitMessageSizes = []int{16 * 1024, 256*1024 - 26}
+
+// maxJoinedEvents bounds how many events are joined into one message.
+const maxJoinedEvents = 64
+
+// eventAssembler rejoins log lines that were split into several events.
+// Events are fed in order with add, which returns the events that are
+// complete so far.
+type eventAssembler struct {
+	pending *logstypes.OutputLogEvent
+	parts   int
+}
+
+func (a *eventAssembler) add(event logstypes.OutputLogEvent) []logstypes.OutputLogEvent {
+	message := aws.ToString(event.Message)
+	if a.pending == nil {
+		if !isSplitSize(message) {
+			return []logstypes.OutputLogEvent{event}
+		}
+		a.pending = &event
+		a.parts = 1
+		return nil
+	}
+	joined := aws.ToString(a.pending.Message) + message
+	a.pending.Message = aws.String(joined)
+	a.pending.IngestionTime = event.IngestionTime
+	a.parts++
+	if isSplitSize(message) && a.parts < maxJoinedEvents && !isCompleteJSON(joined) {
+		return nil
+	}
+	return a.flush()
+}
+
+// flush returns the pending partial message, if any.
+func (a *eventAssembler) flush() []logstypes.OutputLogEvent {
+	if a.pending == nil {
+		return nil
+	}
+	event := *a.pending
+	a.pending = nil
+	return []logstypes.OutputLogEvent{event}
+}
+
+func isSplitSize(message string) bool {
+	for _, size := range splitMessageSizes {
+		if len(message) == size {
+			return true
+		}
+	}
+	return false
+}
+
+// isCompleteJSON reports whether message is a JSON object, which ends a
+// split JSON log line even if the last part happened to be of a split size.
+func isCompleteJSON(message string) bool {
+	return strings.HasPrefix(message, "{") && json.Valid([]byte(message))
+}
+
+// logFilter fetches the events of a log stream matching --filter-pattern,
+// with --filter-logs those of all streams whose name starts with its name.
+// Fetching again returns only events that were not returned before, so a
+// stream can be followed.
+type logFilter struct {
+	svc    *cloudwatchlogs.Client
+	stream logStream
+	// start is the time of the newest event returned so far in milliseconds
+	// of unix time, and seen holds the IDs of the events at that time.
+	start int64
+	seen  map[string]bool
+}
+
+func newLogFilter(svc *cloudwatchlogs.Client, s logStream) *logFilter {
+	f := &logFilter{svc: svc, stream: s, seen: map[string]bool{}}
+	if logsSince > 0 {
+		f.start = time.Now().Add(-logsSince).UnixNano() / int64(time.Millisecond)
+	}
+	return f
+}
+
+// fetch calls handle with every page of new matching events until handle
+// returns false or the end of the stream is reached.
+func (f *logFilter) fetch(ctx context.Context, handle func([]logstypes.OutputLogEvent) bool) error {
+	input := &cloudwatchlogs.FilterLogEventsInput{
+		LogGroupName: aws.String(f.stream.Group),
+	}
+	if filterLogs {
+		input.LogStreamNamePrefix = aws.String(f.stream.Stream)
+	} else {
+		input.LogStreamNames = []string{f.stream.Stream}
+	}
+	if logFilterPattern != "" {
+		input.FilterPattern = aws.String(logFilterPattern)
+	}
+	if f.start > 0 {
+		input.StartTime = aws.Int64(f.start)
+	}
+	paginator := cloudwatchlogs.NewFilterLogEventsPaginator(f.svc, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		var events []logstypes.OutputLogEvent
+		for _, e := range page.Events {
+			id := aws.ToString(e.EventId)
+			if f.seen[id] {
+				continue
+			}
+			if timestamp := aws.ToInt64(e.Timestamp); timestamp > f.start {
+				f.start, f.seen = timestamp, map[string]bool{}
+			}
+			f.seen[id] = true
+			events = append(events, logstypes.OutputLogEvent{
+				Message:       e.Message,
+				Timestamp:     e.Timestamp,
+				IngestionTime: e.IngestionTime,
+			})
+		}
+		if !handle(events) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// followStreams prints new events of several log streams as they arrive
+// until stop is closed, see follow.
+func followStreams(ctx context.Context, cfg aws.Config, streams []logStream, stop <-chan struct{}) {
+	if useLiveTail && followLive(ctx, cfg, streams, stop) {
+		return
+	}
+	out := &syncWriter{w: os.Stdout}
+	var wg sync.WaitGroup
+	for _, s := range streams {
+		wg.Add(1)
+		go func(s logStream) {
+			defer wg.Done()
+			follow(ctx, cfg, s, out, stop)
+		}(s)
+	}
+	wg.Wait()
+}
+
+// follow prints new events of a log stream as they arrive, like docker logs
+// -f, until stop is closed. It then prints the events that arrived since the
+// last poll and returns. The stream is waited for if it does not exist yet.
+func follow(ctx context.Context, cfg aws.Config, s logStream, out io.Writer, stop <-chan struct{}) {
+	svc := cloudwatchlogs.NewFromConfig(cfg)
+	printer := newLogPrinter(out, s)
+	defer printer.flush()
+	if filterMode() {
+		followFiltered(ctx, newLogFilter(svc, s), printer, stop)
+		return
+	}
+	input := logEventsInput(s)
+	stopped := false
+	for {
+		resp, err := svc.GetLogEvents(ctx, input)
+		caughtUp := true
+		switch {
+		case err == nil:
+			printer.print(resp.Events)
+			token := input.NextToken
+			caughtUp = token != nil && aws.ToString(resp.NextForwardToken) == aws.ToString(token)
+			nextLogEvents(input, resp.NextForwardToken)
+		case !isNotFound(err):
+			printError("Error getting log events:", err)
+		}
+		if stopped && caughtUp {
+			return
+		}
+		if !caughtUp {
+			continue
+		}
+		select {
+		case <-stop:
+			stopped = true
+		case <-time.After(pollDelay(0)):
+		}
+	}
+}
+
+// followFiltered prints new events fetched with a logFilter as they arrive
+// until stop is closed, see follow.
+func followFiltered(ctx context.Context, filter *logFilter, printer *logPrinter, stop <-chan struct{}) {
+	stopped := false
+	for {
+		err := filter.fetch(ctx, func(events []logstypes.OutputLogEvent) bool {
+			printer.print(events)
+			return true
+		})
+		if err != nil && !isNotFound(err) {
+			printError("Error filtering log events:", err)
+		}
+		if stopped {
+			return
+		}
+		select {
+		case <-stop:
+			stopped = true
+		case <-time.After(pollDelay(0)):
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	logstypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+func TestEventAssembler(t *testing.T) {
+	docker := strings.Repeat("a", 16*1024)
+	awslogs := strings.Repeat("b", 256*1024-26)
+	jsonLine := `{"message":"` + strings.Repeat("c", 16*1024-len(`{"message":"`)) + strings.Repeat("d", 16*1024-2) + `"}`
+	// The last of maxJoinedEvents parts ends the message even at split size.
+	parts := make([]string, maxJoinedEvents)
+	for i := range parts {
+		parts[i] = docker
+	}
+	tests := []struct {
+		name     string
+		messages []string
+		want     []string
+	}{
+		{"short lines", []string{"one", "two"}, []string{"one", "two"}},
+		{"docker split", []string{docker, "end", "next"}, []string{docker + "end", "next"}},
+		{"several docker parts", []string{docker, docker, "end"}, []string{docker + docker + "end"}},
+		{"awslogs split", []string{awslogs, "end"}, []string{awslogs + "end"}},
+		{"json ends at split size", []string{jsonLine[:16*1024], jsonLine[16*1024:], "next"}, []string{jsonLine, "next"}},
+		{"pending at the end", []string{"one", docker}, []string{"one", docker}},
+		{"too many parts", append(parts, "next"), []string{strings.Join(parts, ""), "next"}},
+	}
+	for _, test := range tests {
+		var a eventAssembler
+		var got []string
+		for i, message := range test.messages {
+			event := logstypes.OutputLogEvent{Message: aws.String(message), IngestionTime: aws.Int64(int64(i))}
+			for _, e := range a.add(event) {
+				got = append(got, aws.ToString(e.Message))
+			}
+		}
+		for _, e := range a.flush() {
+			got = append(got, aws.ToString(e.Message))
+		}
+		if len(got) != len(test.want) {
+			t.Errorf("%s: got %d messages, want %d", test.name, len(got), len(test.want))
+			continue
+		}
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("%s: message %d has %d bytes, want %d", test.name, i, len(got[i]), len(test.want[i]))
+			}
+		}
+	}
+}
+
+func TestEventAssemblerIngestionTime(t *testing.T) {
+	var a eventAssembler
+	a.add(logstypes.OutputLogEvent{Message: aws.String(strings.Repeat("a", 16*1024)), IngestionTime: aws.Int64(1)})
+	events := a.add(logstypes.OutputLogEvent{Message: aws.String("end"), IngestionTime: aws.Int64(2)})
+	if len(events) != 1 || aws.ToInt64(events[0].IngestionTime) != 2 {
+		t.Errorf("joined event should have the ingestion time of its last part, got %v", events)
+	}
+}
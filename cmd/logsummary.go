@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	logstypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+// defaultLogQuery is the Logs Insights query of --log-summary: how many log
+// lines mention errors and warnings.
+const defaultLogQuery = `fields strcontains(@message, "ERROR") as error, strcontains(@message, "WARN") as warning
+| stats sum(error) as errors, sum(warning) as warnings, count(*) as lines`
+
+// logQueryTimeout bounds how long to wait for the results of a query.
+const logQueryTimeout = 2 * time.Minute
+
+var logSummary bool
+var logQuery string
+
+// runLogQuery returns the query of --log-summary and --log-query, or "" if
+// none was requested.
+func runLogQuery() string {
+	if logQuery != "" {
+		return logQuery
+	}
+	if logSummary {
+		return defaultLogQuery
+	}
+	return ""
+}
+
+// printLogSummary runs the Logs Insights query of state against the log
+// streams of its run and prints the results as a table.
+func printLogSummary(ctx context.Context, cfg aws.Config, state *runState) error {
+	if len(state.LogStreams) == 0 {
+		return nil
+	}
+	var groups, streams []string
+	seen := map[string]bool{}
+	for _, s := range state.LogStreams {
+		if !seen[s.Group] {
+			groups = append(groups, s.Group)
+			seen[s.Group] = true
+		}
+		streams = append(streams, strconv.Quote(s.Stream))
+	}
+	svc := cloudwatchlogs.NewFromConfig(cfg)
+	start, err := svc.StartQuery(ctx, &cloudwatchlogs.StartQueryInput{
+		LogGroupNames: groups,
+		QueryString:   aws.String("filter @logStream in [" + strings.Join(streams, ", ") + "]\n| " + state.LogQuery),
+		StartTime:     aws.Int64(state.StartedAt.Add(-time.Minute).Unix()),
+		EndTime:       aws.Int64(time.Now().Add(time.Minute).Unix()),
+	})
+	if err != nil {
+		return err
+	}
+	deadline := time.Now().Add(logQueryTimeout)
+	for attempt := 0; ; attempt++ {
+		output, err := svc.GetQueryResults(ctx, &cloudwatchlogs.GetQueryResultsInput{QueryId: start.QueryId})
+		if err != nil {
+			return err
+		}
+		switch output.Status {
+		case logstypes.QueryStatusComplete:
+			printQueryResults(output.Results)
+			return nil
+		case logstypes.QueryStatusScheduled, logstypes.QueryStatusRunning:
+		default:
+			return fmt.Errorf("log query %s", strings.ToLower(string(output.Status)))
+		}
+		if time.Now().After(deadline) {
+			svc.StopQuery(ctx, &cloudwatchlogs.StopQueryInput{QueryId: start.QueryId})
+			return fmt.Errorf("log query did not complete within %s", logQueryTimeout)
+		}
+		time.Sleep(pollDelay(attempt))
+	}
+}
+
+// printQueryResults prints the rows of a Logs Insights query as a table,
+// leaving out the @ptr field of every row.
+func printQueryResults(rows [][]logstypes.ResultField) {
+	fmt.Println("Log summary:")
+	if len(rows) == 0 {
+		fmt.Println("No matching log lines")
+		return
+	}
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	var header []string
+	for _, field := range rows[0] {
+		if name := aws.ToString(field.Field); name != "@ptr" {
+			header = append(header, strings.ToUpper(name))
+		}
+	}
+	fmt.Fprintln(table, strings.Join(header, "\t"))
+	for _, row := range rows {
+		var values []string
+		for _, field := range row {
+			if aws.ToString(field.Field) != "@ptr" {
+				values = append(values, maskSecrets(aws.ToString(field.Value)))
+			}
+		}
+		fmt.Fprintln(table, strings.Join(values, "\t"))
+	}
+	table.Flush()
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// maskReplacement replaces masked values in log lines.
+const maskReplacement = "***"
+
+var maskFlags []string
+var maskEnvs []string
+
+// masks match the values redacted from printed log lines.
+var masks []*regexp.Regexp
+
+// compileMasks compiles the --mask flags, exiting if one is invalid. Values
+// enclosed in slashes are regular expressions, all others literal values.
+func compileMasks() {
+	for _, m := range maskFlags {
+		if len(m) > 2 && strings.HasPrefix(m, "/") && strings.HasSuffix(m, "/") {
+			re, err := regexp.Compile(m[1 : len(m)-1])
+			if err != nil {
+				fmt.Printf("Invalid --mask %q: %s\n", m, err)
+				exit(1)
+			}
+			masks = append(masks, re)
+			continue
+		}
+		addMask(m)
+	}
+}
+
+// addMask redacts value from printed log lines.
+func addMask(value string) {
+	if value == "" {
+		return
+	}
+	masks = append(masks, regexp.MustCompile(regexp.QuoteMeta(value)))
+}
+
+// maskEnvOverrides redacts the values of the environment overrides named
+// with --mask-env, exiting if one is not set.
+func maskEnvOverrides() {
+	for _, name := range maskEnvs {
+		found := false
+		for _, kv := range envOverrides {
+			if aws.ToString(kv.Name) == name {
+				addMask(aws.ToString(kv.Value))
+				found = true
+			}
+		}
+		for _, overrides := range containerEnvOverrides {
+			for _, kv := range overrides {
+				if aws.ToString(kv.Name) == name {
+					addMask(aws.ToString(kv.Value))
+					found = true
+				}
+			}
+		}
+		if !found {
+			fmt.Printf("Invalid --mask-env %q, no environment variable of that name is passed to the task\n", name)
+			exit(1)
+		}
+	}
+}
+
+// maskSecrets returns message with every masked value replaced.
+func maskSecrets(message string) string {
+	for _, re := range masks {
+		message = re.ReplaceAllLiteralString(message, maskReplacement)
+	}
+	return message
+}
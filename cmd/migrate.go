@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const defaultLockTable = "ecs-run-task-locks"
+
+var migrateTimeout time.Duration
+
+// migrateCmd runs a database migration task with safe defaults
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Run a database migration task",
+	Long: `Run a database migration task with safe defaults:
+
+  - a lock in the DynamoDB table ` + defaultLockTable + ` (see --lock-table) makes sure
+    only one migration per cluster and task family runs at a time
+  - the task is stopped once --timeout is exceeded
+  - the task is never retried
+  - protected clusters require confirmation
+  - an audit record is appended to ~/.ecs-run-task/audit.log (see --audit-log)`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if !cmd.Flags().Changed("lock-table") {
+			lockTable = defaultLockTable
+		}
+		if !cmd.Flags().Changed("audit-log") {
+			auditLog = defaultAuditLog()
+		}
+		if cmd.Flags().Changed("timeout") || !cmd.Flags().Changed("max-run-time") {
+			maxRunTime = migrateTimeout
+		}
+		if retries > 0 {
+			fmt.Println("Migrations are never retried, --retries cannot be used with migrate")
+			os.Exit(1)
+		}
+		runTask(cmd)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(migrateCmd)
+	addRunFlags(migrateCmd.Flags())
+	migrateCmd.Flags().DurationVarP(&migrateTimeout, "timeout", "", 30*time.Minute, "Stop the migration if it runs longer than this")
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+// taskNetworkInterface returns the ID and private IP of the elastic network
+// interface of an awsvpc task, empty for other network modes.
+func taskNetworkInterface(task *ecstypes.Task) (string, string) {
+	var eni, privateIP string
+	for _, attachment := range task.Attachments {
+		for _, detail := range attachment.Details {
+			switch aws.ToString(detail.Name) {
+			case "networkInterfaceId":
+				eni = aws.ToString(detail.Value)
+			case "privateIPv4Address":
+				privateIP = aws.ToString(detail.Value)
+			}
+		}
+	}
+	return eni, privateIP
+}
+
+// publicIP returns the public IP of a network interface, empty if it has
+// none.
+func publicIP(ctx context.Context, cfg aws.Config, eni string) (string, error) {
+	output, err := ec2.NewFromConfig(cfg).DescribeNetworkInterfaces(ctx, &ec2.DescribeNetworkInterfacesInput{
+		NetworkInterfaceIds: []string{eni},
+	})
+	if err != nil {
+		return "", err
+	}
+	for _, ni := range output.NetworkInterfaces {
+		if ni.Association != nil {
+			return aws.ToString(ni.Association.PublicIp), nil
+		}
+	}
+	return "", nil
+}
+
+// printTaskAddresses prints the network interface and IP addresses of a
+// running awsvpc task.
+func printTaskAddresses(ctx context.Context, cfg aws.Config, task *ecstypes.Task) {
+	eni, privateIP := taskNetworkInterface(task)
+	if eni == "" {
+		return
+	}
+	line := fmt.Sprintf("Task %s is running with network interface %s, private IP %s", taskID(aws.ToString(task.TaskArn)), eni, privateIP)
+	ip, err := publicIP(ctx, cfg, eni)
+	if err != nil {
+		printError("Got error looking up public IP:", err)
+	} else if ip != "" {
+		line += ", public IP " + ip
+	}
+	fmt.Println(line)
+}
+
+// addressPrinter returns an onTaskRunning hook printing the addresses of
+// every task once.
+func addressPrinter(ctx context.Context, cfg aws.Config) func(*ecstypes.Task) {
+	var mu sync.Mutex
+	printed := map[string]bool{}
+	return func(task *ecstypes.Task) {
+		mu.Lock()
+		defer mu.Unlock()
+		if printed[aws.ToString(task.TaskArn)] {
+			return
+		}
+		printed[aws.ToString(task.TaskArn)] = true
+		printTaskAddresses(ctx, cfg, task)
+	}
+}
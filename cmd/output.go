@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+var runOutput string
+
+// resultOutput is where the JSON result of a run goes with --output json.
+// It is nil when results are printed as text.
+var resultOutput io.Writer
+
+// runResultPrinted is whether the JSON result of the run was printed.
+var runResultPrinted bool
+
+// runResult is the JSON result of a run printed with --output json.
+type runResult struct {
+	TaskArns       []string     `json:"taskArns,omitempty"`
+	Region         string       `json:"region,omitempty"`
+	Cluster        string       `json:"cluster,omitempty"`
+	TaskDefinition string       `json:"taskDefinition,omitempty"`
+	ExitCode       *int64       `json:"exitCode,omitempty"`
+	ExitReason     string       `json:"exitReason,omitempty"`
+	StopCategory   stopCategory `json:"stopCategory,omitempty"`
+	Tasks          []taskResult `json:"tasks,omitempty"`
+	LogStreams     []logStream  `json:"logStreams,omitempty"`
+	Result         string       `json:"result,omitempty"`
+	StartedAt      *time.Time   `json:"startedAt,omitempty"`
+	Duration       string       `json:"duration,omitempty"`
+	Error          string       `json:"error,omitempty"`
+}
+
+// taskResult is how a task of a run stopped.
+type taskResult struct {
+	TaskArn       string            `json:"taskArn"`
+	StopCode      string            `json:"stopCode,omitempty"`
+	StoppedReason string            `json:"stoppedReason,omitempty"`
+	StopCategory  stopCategory      `json:"stopCategory,omitempty"`
+	Containers    []containerResult `json:"containers"`
+	CreatedAt     *time.Time        `json:"createdAt,omitempty"`
+	StartedAt     *time.Time        `json:"startedAt,omitempty"`
+	StoppedAt     *time.Time        `json:"stoppedAt,omitempty"`
+}
+
+// containerResult is how a container of a task stopped.
+type containerResult struct {
+	Name     string `json:"name"`
+	ExitCode *int32 `json:"exitCode,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// validateOutputFlags exits when --output or --progress have an invalid value.
+func validateOutputFlags() {
+	if runOutput != "text" && runOutput != "json" {
+		fmt.Println("Unknown output format:", runOutput)
+		exit(1)
+	}
+	if progressFormat != "" && progressFormat != "json" {
+		fmt.Println("Unknown progress format:", progressFormat)
+		exit(1)
+	}
+	if runOutput == "json" && progressFormat == "json" {
+		fmt.Println("--output json cannot be used with --progress json, both are printed to stdout")
+		exit(1)
+	}
+}
+
+// redirectOutput sends everything printed for humans to stderr and keeps
+// stdout for the JSON result of --output json or the progress events of
+// --progress json.
+func redirectOutput() {
+	if resultOutput != nil || progressOutput != nil {
+		return
+	}
+	switch {
+	case runOutput == "json":
+		resultOutput = os.Stdout
+		// Consumers of stdout get a result however the run ends.
+		onExit(func() {
+			if !runResultPrinted {
+				code := int64(exitStatus)
+				printRunResult(&runResult{ExitCode: &code, Error: fmt.Sprintf("ecs-run-task exited with code %d, see stderr", exitStatus)})
+			}
+		})
+	case progressFormat == "json":
+		progressOutput = &syncWriter{w: os.Stdout}
+	default:
+		return
+	}
+	os.Stdout = os.Stderr
+}
+
+// newRunResult describes the run of state for --output json.
+func newRunResult(state *runState) *runResult {
+	startedAt := state.StartedAt
+	return &runResult{
+		TaskArns:       state.tasks(),
+		Region:         state.Region,
+		Cluster:        state.Cluster,
+		TaskDefinition: state.TaskDefinition,
+		LogStreams:     state.LogStreams,
+		StartedAt:      &startedAt,
+	}
+}
+
+// newTaskResult describes how task stopped for --output json.
+func newTaskResult(task *ecstypes.Task) taskResult {
+	result := taskResult{
+		TaskArn:       aws.ToString(task.TaskArn),
+		StopCode:      string(task.StopCode),
+		StoppedReason: aws.ToString(task.StoppedReason),
+		StopCategory:  classifyTask(task),
+		Containers:    []containerResult{},
+		CreatedAt:     task.CreatedAt,
+		StartedAt:     task.StartedAt,
+		StoppedAt:     task.StoppedAt,
+	}
+	for _, c := range task.Containers {
+		result.Containers = append(result.Containers, containerResult{
+			Name:     aws.ToString(c.Name),
+			ExitCode: c.ExitCode,
+			Reason:   aws.ToString(c.Reason),
+		})
+	}
+	return result
+}
+
+// printRunResult prints result as JSON to stdout, if --output json was given.
+func printRunResult(result *runResult) {
+	if resultOutput == nil {
+		return
+	}
+	out, _ := json.MarshalIndent(result, "", "  ")
+	fmt.Fprintln(resultOutput, string(out))
+	runResultPrinted = true
+}
@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+// envOverrides are environment variables set on every container of the task.
+var envOverrides []ecstypes.KeyValuePair
+
+// containerEnvOverrides are environment variables set on a single container
+// by name. They take precedence over envOverrides.
+var containerEnvOverrides = map[string][]ecstypes.KeyValuePair{}
+
+// taskRoleArn and executionRoleArn override the roles of the task definition.
+var taskRoleArn string
+var executionRoleArn string
+
+// ephemeralStorage is the size of the Fargate ephemeral storage in GiB, 0
+// to keep the size of the task definition.
+var ephemeralStorage int64
+
+// commandOverrides are the --command flags, each either a command for the
+// first container or container=command.
+var commandOverrides []string
+
+// addEnvOverride sets the environment variable name on every container of
+// the task, replacing an earlier override of the same name.
+func addEnvOverride(name string, value string) {
+	for i, kv := range envOverrides {
+		if *kv.Name == name {
+			envOverrides[i].Value = aws.String(value)
+			return
+		}
+	}
+	envOverrides = append(envOverrides, ecstypes.KeyValuePair{
+		Name:  aws.String(name),
+		Value: aws.String(value),
+	})
+}
+
+// addContainerEnvOverride sets the environment variable name on the
+// container named container, replacing an earlier override of the same name.
+func addContainerEnvOverride(container string, name string, value string) {
+	for i, kv := range containerEnvOverrides[container] {
+		if *kv.Name == name {
+			containerEnvOverrides[container][i].Value = aws.String(value)
+			return
+		}
+	}
+	containerEnvOverrides[container] = append(containerEnvOverrides[container], ecstypes.KeyValuePair{
+		Name:  aws.String(name),
+		Value: aws.String(value),
+	})
+}
+
+// containerEnvironment returns the environment overrides of the container
+// named name.
+func containerEnvironment(name string) []ecstypes.KeyValuePair {
+	scoped := containerEnvOverrides[name]
+	if len(scoped) == 0 {
+		return envOverrides
+	}
+	env := append([]ecstypes.KeyValuePair(nil), scoped...)
+	for _, kv := range envOverrides {
+		overridden := false
+		for _, s := range scoped {
+			overridden = overridden || *s.Name == *kv.Name
+		}
+		if !overridden {
+			env = append(env, kv)
+		}
+	}
+	return env
+}
+
+// taskOverride builds the overrides for taskDefinition. It returns nil
+// when nothing needs to be overridden.
+func taskOverride(taskDefinition *ecstypes.TaskDefinition) *ecstypes.TaskOverride {
+	override := &ecstypes.TaskOverride{ContainerOverrides: containerOverrides(taskDefinition)}
+	if taskRoleArn != "" {
+		override.TaskRoleArn = aws.String(taskRoleArn)
+	}
+	if executionRoleArn != "" {
+		override.ExecutionRoleArn = aws.String(executionRoleArn)
+	}
+	if ephemeralStorage > 0 {
+		override.EphemeralStorage = &ecstypes.EphemeralStorage{SizeInGiB: int32(ephemeralStorage)}
+	}
+	if override.ContainerOverrides == nil && override.TaskRoleArn == nil && override.ExecutionRoleArn == nil && override.EphemeralStorage == nil {
+		return nil
+	}
+	return override
+}
+
+// containerOverrides builds the per container overrides for taskDefinition.
+// It returns nil when nothing needs to be overridden.
+func containerOverrides(taskDefinition *ecstypes.TaskDefinition) []ecstypes.ContainerOverride {
+	commands := containerCommands(taskDefinition)
+	for name := range containerEnvOverrides {
+		if !hasContainer(taskDefinition, name) {
+			fmt.Printf("Invalid --env, task definition has no container named %q\n", name)
+			// exit releases the lock, which is held by now.
+			exit(1)
+		}
+	}
+	if len(envOverrides) == 0 && len(containerEnvOverrides) == 0 && len(commands) == 0 {
+		return nil
+	}
+	var overrides []ecstypes.ContainerOverride
+	for _, container := range taskDefinition.ContainerDefinitions {
+		override := ecstypes.ContainerOverride{Name: container.Name}
+		if env := containerEnvironment(aws.ToString(container.Name)); len(env) > 0 {
+			override.Environment = env
+		}
+		if command, ok := commands[aws.ToString(container.Name)]; ok {
+			override.Command = command
+		}
+		if override.Environment != nil || override.Command != nil {
+			overrides = append(overrides, override)
+		}
+	}
+	return overrides
+}
+
+// validateCommandFlags exits if a --command cannot be split into words,
+// before anything is locked or launched. Container names contain no quotes,
+// so a command splits if the whole flag does.
+func validateCommandFlags() {
+	for _, spec := range commandOverrides {
+		if _, err := splitCommand(spec); err != nil {
+			fmt.Printf("Invalid --command %q: %v\n", spec, err)
+			exit(1)
+		}
+	}
+}
+
+// containerCommands returns the commands of commandOverrides by container
+// name. A command is for a container if it starts with the name of one of
+// the containers of taskDefinition followed by =, otherwise it is for the
+// first container.
+func containerCommands(taskDefinition *ecstypes.TaskDefinition) map[string][]string {
+	commands := map[string][]string{}
+	for _, spec := range commandOverrides {
+		name := aws.ToString(taskDefinition.ContainerDefinitions[0].Name)
+		command := spec
+		if i := strings.Index(spec, "="); i > 0 && hasContainer(taskDefinition, spec[:i]) {
+			name, command = spec[:i], spec[i+1:]
+		}
+		words, err := splitCommand(command)
+		if err != nil {
+			fmt.Printf("Invalid --command %q: %v\n", spec, err)
+			exit(1)
+		}
+		commands[name] = words
+	}
+	return commands
+}
+
+func hasContainer(taskDefinition *ecstypes.TaskDefinition, name string) bool {
+	for _, container := range taskDefinition.ContainerDefinitions {
+		if aws.ToString(container.Name) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// splitCommand splits command into words like a shell would, honoring
+// single and double quotes and backslash escapes. Like in a shell, a
+// backslash in double quotes only escapes $, `, ", \ and newlines, and a
+// backslash before a newline continues the line. Nothing is expanded.
+func splitCommand(command string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, r := range command {
+		switch {
+		case escaped:
+			escaped = false
+			if r == '\n' {
+				break
+			}
+			if quote == '"' && !strings.ContainsRune("$`\"\\", r) {
+				word.WriteRune('\\')
+			}
+			word.WriteRune(r)
+			inWord = true
+		case r == '\\' && quote != '\'':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if escaped {
+		return nil, fmt.Errorf("trailing backslash")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	if len(words) == 0 {
+		return nil, fmt.Errorf("empty command")
+	}
+	return words, nil
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string
+	}{
+		{"echo hello", []string{"echo", "hello"}},
+		{"  echo \t hello\n", []string{"echo", "hello"}},
+		{`sh -c 'echo "$HOME"'`, []string{"sh", "-c", `echo "$HOME"`}},
+		{`echo "a b" c`, []string{"echo", "a b", "c"}},
+		{`echo '' ""`, []string{"echo", "", ""}},
+		{`echo a\ b`, []string{"echo", "a b"}},
+		{`echo 'a\b'`, []string{"echo", `a\b`}},
+		{`echo "a\b"`, []string{"echo", `a\b`}},
+		{`echo "a\"b\\c\$d"`, []string{"echo", `a"b\c$d`}},
+		{"echo a \\\nb", []string{"echo", "a", "b"}},
+		{`echo 'it'\''s'`, []string{"echo", "it's"}},
+		{`a"b"'c'`, []string{"abc"}},
+	}
+	for _, test := range tests {
+		got, err := splitCommand(test.command)
+		if err != nil {
+			t.Errorf("splitCommand(%q) returned error: %v", test.command, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("splitCommand(%q) = %q, want %q", test.command, got, test.want)
+		}
+	}
+}
+
+func TestSplitCommandErrors(t *testing.T) {
+	for _, command := range []string{"", "   ", `echo 'a`, `echo "a`, `echo a\`} {
+		if got, err := splitCommand(command); err == nil {
+			t.Errorf("splitCommand(%q) = %q, want an error", command, got)
+		}
+	}
+}
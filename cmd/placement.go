@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+// placementConstraints are the --placement-constraint flags, each
+// distinctInstance or a cluster query expression for memberOf.
+var placementConstraints []string
+
+// placementStrategies are the --placement-strategy flags, each type[=field].
+var placementStrategies []string
+
+// Most constraints and strategies ECS accepts per task.
+const (
+	maxPlacementConstraints = 10
+	maxPlacementStrategies  = 5
+)
+
+// validatePlacementFlags exits if placement flags are invalid or given for
+// tasks that do not run on EC2.
+func validatePlacementFlags() {
+	if len(placementConstraints) == 0 && len(placementStrategies) == 0 {
+		return
+	}
+	if runsOnFargate() {
+		fmt.Println("--placement-constraint and --placement-strategy only apply to the EC2 launch type, pass --launch-type EC2")
+		exit(1)
+	}
+	if len(placementConstraints) > maxPlacementConstraints {
+		fmt.Printf("Too many --placement-constraint, ECS accepts at most %d\n", maxPlacementConstraints)
+		exit(1)
+	}
+	if len(placementStrategies) > maxPlacementStrategies {
+		fmt.Printf("Too many --placement-strategy, ECS accepts at most %d\n", maxPlacementStrategies)
+		exit(1)
+	}
+	taskPlacementStrategy()
+}
+
+// runsOnFargate reports whether the launch type or capacity providers
+// run the task on Fargate.
+func runsOnFargate() bool {
+	strategy := capacityProviderStrategy()
+	if strategy == nil {
+		return launchType == string(ecstypes.LaunchTypeFargate)
+	}
+	for _, item := range strategy {
+		if strings.HasPrefix(aws.ToString(item.CapacityProvider), "FARGATE") {
+			return true
+		}
+	}
+	return false
+}
+
+// taskPlacementConstraints returns the constraints given with
+// --placement-constraint, nil if there are none.
+func taskPlacementConstraints() []ecstypes.PlacementConstraint {
+	var constraints []ecstypes.PlacementConstraint
+	for _, spec := range placementConstraints {
+		if spec == string(ecstypes.PlacementConstraintTypeDistinctInstance) {
+			constraints = append(constraints, ecstypes.PlacementConstraint{Type: ecstypes.PlacementConstraintTypeDistinctInstance})
+			continue
+		}
+		constraints = append(constraints, ecstypes.PlacementConstraint{
+			Type:       ecstypes.PlacementConstraintTypeMemberOf,
+			Expression: aws.String(spec),
+		})
+	}
+	return constraints
+}
+
+// taskPlacementStrategy returns the strategy given with --placement-strategy,
+// nil if there is none. Strategies are applied in the order given.
+func taskPlacementStrategy() []ecstypes.PlacementStrategy {
+	var strategy []ecstypes.PlacementStrategy
+	for _, spec := range placementStrategies {
+		parts := strings.SplitN(spec, "=", 2)
+		item := ecstypes.PlacementStrategy{Type: ecstypes.PlacementStrategyType(parts[0])}
+		if len(parts) == 2 {
+			item.Field = aws.String(parts[1])
+		}
+		valid := contains(enumValues(item.Type.Values()), parts[0])
+		if item.Type == ecstypes.PlacementStrategyTypeRandom {
+			valid = valid && item.Field == nil
+		} else {
+			valid = valid && item.Field != nil && *item.Field != ""
+		}
+		if !valid {
+			fmt.Printf("Invalid --placement-strategy %q, expected random, spread=FIELD or binpack=cpu|memory\n", spec)
+			exit(1)
+		}
+		strategy = append(strategy, item)
+	}
+	return strategy
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+func TestTaskPlacementStrategy(t *testing.T) {
+	defer func(specs []string) { placementStrategies = specs }(placementStrategies)
+	tests := []struct {
+		specs []string
+		want  []ecstypes.PlacementStrategy
+	}{
+		{nil, nil},
+		{[]string{"random"}, []ecstypes.PlacementStrategy{{Type: ecstypes.PlacementStrategyTypeRandom}}},
+		{[]string{"binpack=memory"}, []ecstypes.PlacementStrategy{{Type: ecstypes.PlacementStrategyTypeBinpack, Field: aws.String("memory")}}},
+		{
+			[]string{"spread=attribute:ecs.availability-zone", "binpack=cpu"},
+			[]ecstypes.PlacementStrategy{
+				{Type: ecstypes.PlacementStrategyTypeSpread, Field: aws.String("attribute:ecs.availability-zone")},
+				{Type: ecstypes.PlacementStrategyTypeBinpack, Field: aws.String("cpu")},
+			},
+		},
+	}
+	for _, test := range tests {
+		placementStrategies = test.specs
+		got := taskPlacementStrategy()
+		if len(got) != len(test.want) {
+			t.Errorf("taskPlacementStrategy() with %q returned %d items, want %d", test.specs, len(got), len(test.want))
+			continue
+		}
+		for i := range got {
+			if got[i].Type != test.want[i].Type || aws.ToString(got[i].Field) != aws.ToString(test.want[i].Field) {
+				t.Errorf("taskPlacementStrategy() with %q item %d = %s=%s, want %s=%s", test.specs, i,
+					got[i].Type, aws.ToString(got[i].Field), test.want[i].Type, aws.ToString(test.want[i].Field))
+			}
+		}
+	}
+}
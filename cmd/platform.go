@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+// cpuArchitecture and osFamily select the runtime platform of task
+// definitions registered by this tool and are checked against existing ones.
+var cpuArchitecture string
+var osFamily string
+
+// validatePlatformFlags exits if --cpu-architecture or --os-family has an
+// invalid value or cannot run with the other run flags.
+func validatePlatformFlags() {
+	architectures := enumValues(ecstypes.CPUArchitecture("").Values())
+	if cpuArchitecture != "" && !contains(architectures, cpuArchitecture) {
+		fmt.Printf("Invalid --cpu-architecture %q, expected one of %s\n", cpuArchitecture, strings.Join(architectures, ", "))
+		exit(1)
+	}
+	families := enumValues(ecstypes.OSFamily("").Values())
+	if osFamily != "" && !contains(families, osFamily) {
+		fmt.Printf("Invalid --os-family %q, expected one of %s\n", osFamily, strings.Join(families, ", "))
+		exit(1)
+	}
+	if isWindows(osFamily) && fargateSpot {
+		fmt.Println("Fargate Spot does not support Windows containers")
+		exit(1)
+	}
+	if isWindows(osFamily) && cpuArchitecture == string(ecstypes.CPUArchitectureArm64) {
+		fmt.Println("Windows containers only run on X86_64")
+		exit(1)
+	}
+	if cpuArchitecture == string(ecstypes.CPUArchitectureArm64) && isDeprecatedPlatformVersion(platformVersion) {
+		fmt.Println("ARM64 on Fargate requires platform version 1.4.0 or LATEST")
+		exit(1)
+	}
+}
+
+// runtimePlatform returns the runtime platform to register task definitions
+// with, nil if neither flag was set.
+func runtimePlatform() *ecstypes.RuntimePlatform {
+	if cpuArchitecture == "" && osFamily == "" {
+		return nil
+	}
+	return &ecstypes.RuntimePlatform{
+		CpuArchitecture:       ecstypes.CPUArchitecture(cpuArchitecture),
+		OperatingSystemFamily: ecstypes.OSFamily(osFamily),
+	}
+}
+
+// checkRuntimePlatform exits if taskDefinition is registered for another
+// runtime platform than --cpu-architecture and --os-family ask for. Task
+// definitions without a runtime platform run on Linux X86_64.
+func checkRuntimePlatform(taskDefinition *ecstypes.TaskDefinition) {
+	architecture, family := string(ecstypes.CPUArchitectureX8664), string(ecstypes.OSFamilyLinux)
+	if platform := taskDefinition.RuntimePlatform; platform != nil {
+		if platform.CpuArchitecture != "" {
+			architecture = string(platform.CpuArchitecture)
+		}
+		if platform.OperatingSystemFamily != "" {
+			family = string(platform.OperatingSystemFamily)
+		}
+	}
+	name := fmt.Sprintf("%s:%d", aws.ToString(taskDefinition.Family), taskDefinition.Revision)
+	if cpuArchitecture != "" && cpuArchitecture != architecture {
+		exitWithError("Got error checking runtime platform:", fmt.Errorf("task definition %s is registered for %s, not %s", name, architecture, cpuArchitecture))
+	}
+	if osFamily != "" && osFamily != family {
+		exitWithError("Got error checking runtime platform:", fmt.Errorf("task definition %s is registered for %s, not %s", name, family, osFamily))
+	}
+}
+
+func isWindows(family string) bool {
+	return strings.HasPrefix(family, "WINDOWS")
+}
+
+// enumValues returns the values of an SDK enum as strings.
+func enumValues[T ~string](values []T) []string {
+	strs := make([]string, len(values))
+	for i, v := range values {
+		strs[i] = string(v)
+	}
+	return strs
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/spf13/cobra"
+)
+
+// portForwardingDocument is the SSM document forwarding a local port to a
+// port of the session target.
+const portForwardingDocument = "AWS-StartPortForwardingSession"
+
+// portForwardCmd forwards a local port to a port of a running task
+var portForwardCmd = &cobra.Command{
+	Use:   "port-forward <task-arn> <local-port>:<remote-port>",
+	Short: "Forward a local port to a port of a running task",
+	Long: `Forward a local port to a port of a container of a running task through an
+SSM session, e.g. to reach a debug HTTP server or a database in a one-off
+task from localhost. A single port forwards to the same port of the task.
+
+Like exec, this needs a task launched with --enable-execute-command and the
+Session Manager plugin for the AWS CLI. The task can be given as ARN, or as
+ID together with --cluster.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		localPort, remotePort, err := parsePortMapping(args[1])
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		ctx := cmd.Context()
+		cfg, cluster, task, container := findExecTarget(ctx, args[0])
+		target := execTarget(cluster, task, container)
+		parameters := map[string][]string{
+			"portNumber":      {strconv.Itoa(remotePort)},
+			"localPortNumber": {strconv.Itoa(localPort)},
+		}
+		output, err := ssm.NewFromConfig(cfg).StartSession(ctx, &ssm.StartSessionInput{
+			Target:       aws.String(target),
+			DocumentName: aws.String(portForwardingDocument),
+			Parameters:   parameters,
+		})
+		if err != nil {
+			exitWithError("Got error starting port forwarding session:", err)
+		}
+		fmt.Printf("Forwarding localhost:%d to port %d of container %s, press Ctrl-C to stop\n", localPort, remotePort, aws.ToString(container.Name))
+		err = startSession(cfg, output.SessionId, output.StreamUrl, output.TokenValue, map[string]interface{}{
+			"Target":       target,
+			"DocumentName": portForwardingDocument,
+			"Parameters":   parameters,
+		})
+		if err != nil {
+			exitWithError("Got error running the Session Manager plugin:", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(portForwardCmd)
+	portForwardCmd.Flags().StringVarP(&ecsCluster, "cluster", "c", "", "Name of the Cluster, if the task is given by ID")
+	portForwardCmd.Flags().StringVarP(&execContainer, "container", "", "", "Container to forward to (default the first container)")
+}
+
+// parsePortMapping parses local:remote, or a single port used for both.
+func parsePortMapping(spec string) (int, int, error) {
+	parts := strings.Split(spec, ":")
+	if len(parts) > 2 {
+		return 0, 0, fmt.Errorf("invalid port mapping %q, expected local:remote", spec)
+	}
+	var ports []int
+	for _, part := range parts {
+		port, err := strconv.Atoi(part)
+		if err != nil || port < 1 || port > 65535 {
+			return 0, 0, fmt.Errorf("invalid port %q in %q, expected 1 to 65535", part, spec)
+		}
+		ports = append(ports, port)
+	}
+	return ports[0], ports[len(ports)-1], nil
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+var progressFormat string
+
+// progressOutput is where progress events go with --progress json. It is
+// nil when no events are emitted.
+var progressOutput io.Writer
+
+// Progress events, see progressEvent.
+const (
+	progressTaskLaunched  = "task-launched"
+	progressStatusChanged = "status-changed"
+	progressLogLine       = "log-line"
+	progressTaskStopped   = "task-stopped"
+)
+
+// progressEvent is a line of the NDJSON progress stream of --progress json.
+type progressEvent struct {
+	Event        string       `json:"event"`
+	Time         time.Time    `json:"time"`
+	TaskArn      string       `json:"taskArn,omitempty"`
+	Cluster      string       `json:"cluster,omitempty"`
+	Region       string       `json:"region,omitempty"`
+	Status       string       `json:"status,omitempty"`
+	LogGroup     string       `json:"logGroup,omitempty"`
+	LogStream    string       `json:"logStream,omitempty"`
+	Message      *string      `json:"message,omitempty"`
+	ExitCode     *int64       `json:"exitCode,omitempty"`
+	ExitReason   string       `json:"exitReason,omitempty"`
+	StopCategory stopCategory `json:"stopCategory,omitempty"`
+}
+
+// emitProgress writes event as a JSON line, if --progress json was given.
+// The event is timestamped now unless it has a time already.
+func emitProgress(event progressEvent) {
+	if progressOutput == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	line, _ := json.Marshal(event)
+	progressOutput.Write(append(line, '\n'))
+}
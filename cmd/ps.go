@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/spf13/cobra"
+)
+
+var psStatus string
+var psStartedBy string
+var psGroup string
+var psAll bool
+
+// psCmd lists tasks launched by this tool
+var psCmd = &cobra.Command{
+	Use:     "ps",
+	Aliases: []string{"list"},
+	Short:   "List tasks launched by ecs-run-task",
+	Long: `List running and recently stopped tasks launched by ecs-run-task with their
+task definition, status, uptime and exit code. ECS keeps stopped tasks for
+about an hour.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if ecsCluster == "" {
+			cmd.Usage()
+			os.Exit(1)
+		}
+		var statuses []ecstypes.DesiredStatus
+		switch psStatus {
+		case "running":
+			statuses = []ecstypes.DesiredStatus{ecstypes.DesiredStatusRunning}
+		case "stopped":
+			statuses = []ecstypes.DesiredStatus{ecstypes.DesiredStatusStopped}
+		case "all":
+			statuses = []ecstypes.DesiredStatus{ecstypes.DesiredStatusRunning, ecstypes.DesiredStatusStopped}
+		default:
+			fmt.Printf("Invalid --status %q, expected running, stopped or all\n", psStatus)
+			os.Exit(1)
+		}
+		ctx := cmd.Context()
+		svc := ecs.NewFromConfig(newConfig(ctx))
+		var arns []string
+		for _, status := range statuses {
+			input := &ecs.ListTasksInput{
+				Cluster:       aws.String(ecsCluster),
+				DesiredStatus: status,
+			}
+			if psStartedBy != "" {
+				input.StartedBy = aws.String(psStartedBy)
+			}
+			paginator := ecs.NewListTasksPaginator(svc, input)
+			for paginator.HasMorePages() {
+				page, err := paginator.NextPage(ctx)
+				if err != nil {
+					exitWithError("Got error listing tasks:", err)
+				}
+				arns = append(arns, page.TaskArns...)
+			}
+		}
+		tasks, err := describeTasks(ctx, svc, ecsCluster, arns)
+		if err != nil {
+			exitWithError("Got error describing tasks:", err)
+		}
+		sort.Slice(tasks, func(i, j int) bool {
+			return aws.ToTime(tasks[i].CreatedAt).After(aws.ToTime(tasks[j].CreatedAt))
+		})
+
+		table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(table, "TASK\tDEFINITION\tSTATUS\tUPTIME\tEXIT CODE\tSTARTED BY")
+		for i, task := range tasks {
+			startedBy := aws.ToString(task.StartedBy)
+			if !psAll && psStartedBy == "" && !strings.HasPrefix(startedBy, startedByPrefix) {
+				continue
+			}
+			if psGroup != "" && aws.ToString(task.Group) != psGroup {
+				continue
+			}
+			exitCode := ""
+			if len(task.Containers) > 0 && task.Containers[0].ExitCode != nil {
+				exitCode = fmt.Sprint(*task.Containers[0].ExitCode)
+			}
+			fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\n",
+				taskID(aws.ToString(task.TaskArn)),
+				taskDefinitionName(aws.ToString(task.TaskDefinitionArn)),
+				aws.ToString(task.LastStatus),
+				taskUptime(&tasks[i]).Round(time.Second),
+				exitCode,
+				startedBy)
+		}
+		table.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(psCmd)
+	psCmd.Flags().StringVarP(&ecsCluster, "cluster", "c", "", "Name of the Cluster")
+	psCmd.Flags().StringVarP(&psStatus, "status", "", "all", "Tasks to list: running, stopped or all")
+	psCmd.Flags().StringVarP(&psStartedBy, "started-by", "", "", "Only list tasks with exactly this startedBy value")
+	psCmd.Flags().StringVarP(&psGroup, "group", "", "", "Only list tasks in this task group")
+	psCmd.Flags().BoolVarP(&psAll, "all", "a", false, "Also list tasks not launched by ecs-run-task")
+}
+
+// taskDefinitionName returns family:revision of a task definition ARN.
+func taskDefinitionName(taskDefinitionArn string) string {
+	return taskDefinitionArn[strings.LastIndex(taskDefinitionArn, "/")+1:]
+}
+
+// taskUptime returns how long the task has been running, or ran if it stopped.
+func taskUptime(task *ecstypes.Task) time.Duration {
+	if task.StartedAt == nil {
+		return 0
+	}
+	if task.StoppedAt != nil {
+		return task.StoppedAt.Sub(*task.StartedAt)
+	}
+	return time.Since(*task.StartedAt)
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cwtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/servicequotas"
+)
+
+var checkQuotas bool
+
+// serviceQuota identifies an entry in Service Quotas.
+type serviceQuota struct {
+	name        string
+	serviceCode string
+	quotaCode   string
+}
+
+// quotaHints lists failure messages caused by hitting a service quota.
+// Patterns are matched case-insensitively.
+var quotaHints = []struct {
+	pattern string
+	quota   serviceQuota
+}{
+	{"limit on the number of vCPUs", serviceQuota{"Fargate On-Demand vCPU resource count", "fargate", "L-3032A538"}},
+	{"maximum number of network interfaces", serviceQuota{"Network interfaces per Region", "vpc", "L-DF5E4CA3"}},
+	{"NetworkInterfaceLimitExceeded", serviceQuota{"Network interfaces per Region", "vpc", "L-DF5E4CA3"}},
+	{"limit on the number of tasks you can run concurrently", serviceQuota{"Tasks in the PROVISIONING state per cluster", "ecs", ""}},
+	{"Too many concurrent attempts to create a new revision", serviceQuota{"Task definition revisions", "ecs", ""}},
+}
+
+// printQuotaHints tells which Service Quotas entry to raise when text is a
+// quota failure, and with --check-quotas looks up its value and usage.
+func printQuotaHints(text string) {
+	lower := strings.ToLower(text)
+	for _, h := range quotaHints {
+		if !strings.Contains(lower, strings.ToLower(h.pattern)) {
+			continue
+		}
+		q := h.quota
+		fmt.Printf("%s This looks like a service quota. Request an increase of %q for %s in the Service Quotas console.\n", colorize(colorYellow, "Hint:"), q.name, q.serviceCode)
+		fmt.Println("See: https://console.aws.amazon.com/servicequotas/home/services/" + q.serviceCode + "/quotas")
+		if checkQuotas && q.quotaCode != "" {
+			if err := printQuotaUsage(context.Background(), q); err != nil {
+				fmt.Println("Could not look up the quota:", err)
+			}
+		}
+		return
+	}
+}
+
+// printQuotaUsage prints the applied value of q and, when Service Quotas
+// tracks it, the peak usage over the last hour.
+func printQuotaUsage(ctx context.Context, q serviceQuota) error {
+	cfg := newConfig(ctx)
+	output, err := servicequotas.NewFromConfig(cfg).GetServiceQuota(ctx, &servicequotas.GetServiceQuotaInput{
+		ServiceCode: aws.String(q.serviceCode),
+		QuotaCode:   aws.String(q.quotaCode),
+	})
+	if err != nil {
+		return err
+	}
+	quota := output.Quota
+	fmt.Printf("Current %s quota: %g\n", q.name, aws.ToFloat64(quota.Value))
+	metric := quota.UsageMetric
+	if metric == nil || metric.MetricName == nil {
+		return nil
+	}
+	var dimensions []cwtypes.Dimension
+	for name, value := range metric.MetricDimensions {
+		dimensions = append(dimensions, cwtypes.Dimension{Name: aws.String(name), Value: aws.String(value)})
+	}
+	stat := cwtypes.Statistic(aws.ToString(metric.MetricStatisticRecommendation))
+	if stat == "" {
+		stat = cwtypes.StatisticMaximum
+	}
+	now := time.Now()
+	usage, err := cloudwatch.NewFromConfig(cfg).GetMetricStatistics(ctx, &cloudwatch.GetMetricStatisticsInput{
+		Namespace:  metric.MetricNamespace,
+		MetricName: metric.MetricName,
+		Dimensions: dimensions,
+		StartTime:  aws.Time(now.Add(-time.Hour)),
+		EndTime:    aws.Time(now),
+		Period:     aws.Int32(3600),
+		Statistics: []cwtypes.Statistic{stat},
+	})
+	if err != nil {
+		return err
+	}
+	peak := 0.0
+	for _, point := range usage.Datapoints {
+		for _, v := range []*float64{point.Maximum, point.Average, point.Sum} {
+			if v != nil && *v > peak {
+				peak = *v
+			}
+		}
+	}
+	fmt.Printf("Peak usage in the last hour: %g\n", peak)
+	return nil
+}
@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/spf13/cobra"
+)
+
+// startedByPrefix starts the default startedBy value of tasks launched by
+// this tool, see startedBy.
+const startedByPrefix = "ecs-run-task"
+
+// reapStopReason is the stop reason of tasks stopped by reap.
+const reapStopReason = "ecs-run-task: reaped orphaned task"
+
+var reapOlderThan time.Duration
+var reapDryRun bool
+var reapAll bool
+
+// reapCmd stops tasks left behind by interrupted runs
+var reapCmd = &cobra.Command{
+	Use:   "reap",
+	Short: "Stop orphaned tasks launched by ecs-run-task",
+	Long: `Stop tasks launched by ecs-run-task that have been running for longer
+than --older-than and whose run is no longer being watched, cleaning up after
+crashed CI agents and interrupted runs.
+
+Only tasks launched from this machine by a process that is no longer running
+are stopped. With --all, tasks launched elsewhere, e.g. from CI or with
+--no-wait, are stopped as well, as only their age is known; they are listed
+and have to be confirmed first unless --yes is passed.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if ecsCluster == "" {
+			cmd.Usage()
+			os.Exit(1)
+		}
+		if !reapDryRun {
+			confirmProtectedCluster(ecsCluster)
+		}
+		ctx := cmd.Context()
+		svc := ecs.NewFromConfig(newConfig(ctx))
+		var arns []string
+		paginator := ecs.NewListTasksPaginator(svc, &ecs.ListTasksInput{
+			Cluster:       aws.String(ecsCluster),
+			DesiredStatus: ecstypes.DesiredStatusRunning,
+		})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				exitWithError("Got error listing tasks:", err)
+			}
+			arns = append(arns, page.TaskArns...)
+		}
+		tasks, err := describeTasks(ctx, svc, ecsCluster, arns)
+		if err != nil {
+			exitWithError("Got error describing tasks:", err)
+		}
+		var orphans []ecstypes.Task
+		for _, task := range tasks {
+			if !strings.HasPrefix(aws.ToString(task.StartedBy), startedByPrefix) {
+				continue
+			}
+			age := time.Since(aws.ToTime(task.CreatedAt))
+			if age < reapOlderThan {
+				continue
+			}
+			local, watched := localRun(aws.ToString(task.TaskArn))
+			if watched || !local && !reapAll {
+				continue
+			}
+			fmt.Printf("%s %s running for %s\n", taskID(aws.ToString(task.TaskArn)), taskFamily(aws.ToString(task.TaskDefinitionArn)), age.Round(time.Second))
+			orphans = append(orphans, task)
+		}
+		if reapDryRun || len(orphans) == 0 {
+			return
+		}
+		if reapAll && !confirmReap(len(orphans)) {
+			fmt.Println("Aborted")
+			os.Exit(1)
+		}
+		reaped := 0
+		for _, task := range orphans {
+			_, err := svc.StopTask(ctx, &ecs.StopTaskInput{
+				Cluster: aws.String(ecsCluster),
+				Task:    task.TaskArn,
+				Reason:  aws.String(reapStopReason),
+			})
+			if err != nil {
+				printError("Got error stopping task "+taskID(aws.ToString(task.TaskArn))+":", err)
+				continue
+			}
+			removeState(&runState{TaskArn: aws.ToString(task.TaskArn)})
+			reaped++
+		}
+		fmt.Printf("Stopped %d orphaned tasks\n", reaped)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(reapCmd)
+	reapCmd.Flags().StringVarP(&ecsCluster, "cluster", "c", "", "Name of the Cluster")
+	reapCmd.Flags().DurationVarP(&reapOlderThan, "older-than", "", 2*time.Hour, "Only stop tasks running for longer than this")
+	reapCmd.Flags().BoolVarP(&reapDryRun, "dry-run", "", false, "Only list the tasks that would be stopped")
+	reapCmd.Flags().BoolVarP(&reapAll, "all", "", false, "Also stop tasks that were not launched from this machine")
+	reapCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Do not ask for confirmation in a protected cluster or before stopping tasks with --all")
+}
+
+// localRun reports whether the run of taskArn was started on this machine,
+// and whether the process that started it is still running.
+func localRun(taskArn string) (local bool, watched bool) {
+	ids, _ := listStates()
+	for _, id := range ids {
+		state, err := loadState(id)
+		if err != nil {
+			continue
+		}
+		for _, arn := range state.tasks() {
+			if taskID(arn) == taskID(taskArn) {
+				local = true
+				if isRunningOwner(state.Owner) {
+					return true, true
+				}
+			}
+		}
+	}
+	return local, false
+}
+
+// confirmReap asks whether to stop the n listed tasks. Without a terminal
+// to ask on it needs --yes.
+func confirmReap(n int) bool {
+	if assumeYes {
+		return true
+	}
+	if !isTerminal(os.Stdin) {
+		fmt.Println("Pass --yes to stop tasks with --all non-interactively")
+		return false
+	}
+	fmt.Printf("Stop these %d tasks? [y/N] ", n)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// isRunningOwner reports whether owner, as returned by lockOwner, is a
+// process on this machine that is still running.
+func isRunningOwner(owner string) bool {
+	if owner == "" {
+		return false
+	}
+	host, _ := os.Hostname()
+	at := strings.LastIndex(owner, "@")
+	colon := strings.LastIndex(owner, ":")
+	if at < 0 || colon < at || owner[at+1:colon] != host {
+		return false
+	}
+	pid, err := strconv.Atoi(owner[colon+1:])
+	if err != nil {
+		return false
+	}
+	return processRunning(pid)
+}
+
+func processRunning(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	// On Windows FindProcess already fails for processes that are gone.
+	if runtime.GOOS == "windows" {
+		return true
+	}
+	return process.Signal(syscall.Signal(0)) == nil
+}
@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// fanOutRegions is the comma separated list of regions to run the task in
+// at the same time.
+var fanOutRegions string
+
+// childRun is the outcome of a run by a copy of this process.
+type childRun struct {
+	label    string
+	exitCode int
+	duration time.Duration
+	err      error
+}
+
+// runInRegions runs the task in every region of fanOutRegions in parallel,
+// prints their output prefixed with the region and a summary table, and
+// exits with 0 if every run succeeded. Each region is run by a copy of this
+// process with the same arguments.
+func runInRegions() {
+	if stdinTo != "" {
+		fmt.Println("--stdin-to cannot be used with --regions")
+		os.Exit(1)
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		exitWithError("Got error finding the ecs-run-task executable:", err)
+	}
+	// An empty --regions keeps a preset of run from fanning out the
+	// children again.
+	args := withFlags(withoutRegionsFlag(os.Args[1:]), "--regions=", "--yes")
+	regions := strings.Split(fanOutRegions, ",")
+	runs := make([]childRun, len(regions))
+	out := &syncWriter{w: os.Stdout}
+	var wg sync.WaitGroup
+	for i, region := range regions {
+		wg.Add(1)
+		go func(i int, region string) {
+			defer wg.Done()
+			region = strings.TrimSpace(region)
+			env := []string{"AWS_REGION=" + region, "AWS_DEFAULT_REGION=" + region}
+			runs[i] = runChild(executable, args, env, region, out, nil)
+		}(i, region)
+	}
+	wg.Wait()
+
+	fmt.Println("Results:")
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "REGION\tEXIT CODE\tDURATION\tSTATUS")
+	succeeded := true
+	for _, run := range runs {
+		status := "SUCCEEDED"
+		if run.err != nil {
+			status = "FAILED: " + run.err.Error()
+		} else if run.exitCode != 0 {
+			status = "FAILED"
+		}
+		if status != "SUCCEEDED" {
+			succeeded = false
+		}
+		fmt.Fprintf(table, "%s\t%d\t%s\t%s\n", run.label, run.exitCode, run.duration.Round(time.Second), status)
+	}
+	table.Flush()
+	printStatus(succeeded, fmt.Sprintf("%d regions", len(runs)))
+	if !succeeded {
+		exit(1)
+	}
+	exit(0)
+}
+
+// runChild runs executable with args and the extra environment variables in
+// env, and copies its output to out with every line prefixed with label.
+// onLine, if not nil, is called with every line of output.
+func runChild(executable string, args []string, env []string, label string, out io.Writer, onLine func(string)) childRun {
+	run := childRun{label: label}
+	started := time.Now()
+	reader, writer := io.Pipe()
+	child := exec.Command(executable, args...)
+	child.Env = append(os.Environ(), env...)
+	child.Stdout = writer
+	child.Stderr = writer
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		scanner := bufio.NewScanner(reader)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			fmt.Fprintf(out, "[%s] %s\n", label, scanner.Text())
+			if onLine != nil {
+				onLine(scanner.Text())
+			}
+		}
+		io.Copy(ioutil.Discard, reader)
+	}()
+	err := child.Run()
+	writer.Close()
+	<-done
+	run.duration = time.Since(started)
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		run.exitCode = exitErr.ExitCode()
+	} else if err != nil {
+		run.exitCode = 1
+		run.err = err
+	}
+	return run
+}
+
+// withoutRegionsFlag returns args without --regions and its value. Arguments
+// after -- belong to the command of run-image and are kept as they are.
+func withoutRegionsFlag(args []string) []string {
+	var result []string
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--":
+			return append(result, args[i:]...)
+		case args[i] == "--regions":
+			i++
+		case strings.HasPrefix(args[i], "--regions="):
+		default:
+			result = append(result, args[i])
+		}
+	}
+	return result
+}
+
+// withFlags returns args with flags added before the first --, so they are
+// parsed as flags rather than passed on as arguments of the command.
+func withFlags(args []string, flags ...string) []string {
+	for i, arg := range args {
+		if arg == "--" {
+			return append(append(append([]string{}, args[:i]...), flags...), args[i:]...)
+		}
+	}
+	return append(args, flags...)
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChildArgs(t *testing.T) {
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{
+			[]string{"run", "-c", "jobs", "--regions", "eu-west-1,us-east-1"},
+			[]string{"run", "-c", "jobs", "--regions=", "--yes"},
+		},
+		{
+			[]string{"run", "--regions=eu-west-1,us-east-1", "-c", "jobs"},
+			[]string{"run", "-c", "jobs", "--regions=", "--yes"},
+		},
+		{
+			[]string{"run-image", "--image", "app:v1", "--regions", "eu-west-1", "--", "run", "--regions", "x"},
+			[]string{"run-image", "--image", "app:v1", "--regions=", "--yes", "--", "run", "--regions", "x"},
+		},
+	}
+	for _, test := range tests {
+		got := withFlags(withoutRegionsFlag(test.args), "--regions=", "--yes")
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("child args of %q = %q, want %q", test.args, got, test.want)
+		}
+	}
+}
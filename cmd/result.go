@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+)
+
+// resultEnvName is the environment variable telling the task where to write
+// its JSON result when --collect-result is used.
+const resultEnvName = "ECS_RUN_TASK_RESULT"
+
+var collectResult string
+
+// resultLocation returns a location below base that is unique to this run.
+// base is either s3://bucket/prefix or ssm:/parameter/prefix.
+func resultLocation(base string) string {
+	if strings.HasPrefix(base, "ssm:") {
+		return strings.TrimSuffix(base, "/") + "/" + runID()
+	}
+	return strings.TrimSuffix(base, "/") + "/" + runID() + "/result.json"
+}
+
+// readResult fetches the JSON result a task wrote to location. SSM
+// parameters are deleted once read, S3 objects are kept.
+func readResult(ctx context.Context, cfg aws.Config, location string) ([]byte, error) {
+	var result []byte
+	if strings.HasPrefix(location, "ssm:") {
+		name := strings.TrimPrefix(location, "ssm:")
+		svc := ssm.NewFromConfig(cfg)
+		output, err := svc.GetParameter(ctx, &ssm.GetParameterInput{
+			Name:           aws.String(name),
+			WithDecryption: aws.Bool(true),
+		})
+		if err != nil {
+			return nil, err
+		}
+		result = []byte(*output.Parameter.Value)
+		svc.DeleteParameter(ctx, &ssm.DeleteParameterInput{Name: aws.String(name)})
+	} else {
+		bucket, key, err := parseS3URI(location)
+		if err != nil {
+			return nil, err
+		}
+		output, err := newS3Client(cfg).GetObject(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			return nil, err
+		}
+		defer output.Body.Close()
+		result, err = ioutil.ReadAll(output.Body)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, result, "", "  "); err != nil {
+		return nil, fmt.Errorf("result at %s is not valid JSON: %v", location, err)
+	}
+	return indented.Bytes(), nil
+}
+
+// runID returns an identifier unique to this invocation.
+func runID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return time.Now().UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(b)
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// resumeCmd picks up monitoring a task whose run was interrupted
+var resumeCmd = &cobra.Command{
+	Use:   "resume [task-id]",
+	Short: "Resume monitoring a task after ecs-run-task was interrupted",
+	Long: `Resume monitoring a task after ecs-run-task crashed or was killed.
+
+Every launched task is recorded in ~/.ecs-run-task/state until its run
+completes. resume waits for the task, prints its logs and exits with its
+exit code, with the settings of the original run. Without a task ID the only
+interrupted run is resumed.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var id string
+		if len(args) == 1 {
+			id = args[0]
+		} else {
+			ids, err := listStates()
+			if err != nil {
+				exitWithError("Got error listing interrupted runs:", err)
+			}
+			switch len(ids) {
+			case 0:
+				fmt.Println("There are no interrupted runs to resume")
+				os.Exit(1)
+			case 1:
+				id = ids[0]
+			default:
+				fmt.Println("There are several interrupted runs, pass the ID of the task to resume:")
+				for _, id := range ids {
+					fmt.Println(" ", id)
+				}
+				os.Exit(1)
+			}
+		}
+		state, err := loadState(id)
+		if err != nil {
+			exitWithError("Got error reading state of task "+id+":", err)
+		}
+		state.Owner = lockOwner()
+		if err := saveState(state); err != nil {
+			printError("Got error saving state:", err)
+		}
+		ctx := cmd.Context()
+		cfg := newRegionConfig(ctx, state.Region)
+		if state.LockTable != "" {
+			releaseLockOnExit(ctx, state.lockConfig(ctx, cfg), state.LockTable, state.LockKey, state.LockOwner)
+		}
+		fmt.Printf("Resuming task %s in an ECS Cluster %s...\n", state.TaskArn, state.Cluster)
+		auditOnExit(state)
+		monitorTask(ctx, cfg, state)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(resumeCmd)
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Stop reasons --retry-on retries a failed run for.
+const (
+	retryOnFailure        = "failure"
+	retryOnInfrastructure = "infrastructure"
+)
+
+var retries int
+var retryOn string
+
+// validateRetryFlags exits if --retries or --retry-on have an invalid value.
+func validateRetryFlags() {
+	if retries < 0 {
+		fmt.Printf("Invalid --retries %d, expected at least 0\n", retries)
+		exit(1)
+	}
+	if retryOn != retryOnFailure && retryOn != retryOnInfrastructure {
+		fmt.Printf("Invalid --retry-on %q, expected %s or %s\n", retryOn, retryOnFailure, retryOnInfrastructure)
+		exit(1)
+	}
+}
+
+// shouldRetry reports whether a run that ended like result is retried.
+// Interrupted runs are never retried.
+func shouldRetry(result taskExit) bool {
+	if result.exitCode == 0 || strings.HasPrefix(result.exitReason, interruptStopReason) {
+		return false
+	}
+	if retryOn == retryOnInfrastructure {
+		_, ok := infrastructureExitCodes[result.category]
+		return ok
+	}
+	return true
+}
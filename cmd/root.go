@@ -1,19 +1,24 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
-	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	logstypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
 )
 
 var ecsCluster string
@@ -21,32 +26,34 @@ var taskDefinition string
 var taskDefinitionFile bool
 var securityGroups string
 var subnets string
+var assignPublicIP bool
+var taskCount int
+var noWait bool
+var taskArnFile string
+
+// placementRetries is how often launching is retried when ECS cannot place
+// the task.
+var placementRetries int
 var launchType string
+var cfgFile string
+
+// maxRunTime is how long a task may run before it is stopped, 0 means no limit.
+var maxRunTime time.Duration
+
+// timeoutStopReason prefixes the stop reason of tasks stopped for exceeding
+// maxRunTime.
+const timeoutStopReason = "ecs-run-task: exceeded maximum run time"
+
+// exitCodeTimeout is the exit code used when the task was stopped for
+// exceeding maxRunTime.
+const exitCodeTimeout = 124
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "ecs-run-task",
 	Short: "A tool for running a task in an ECS cluster",
 	Run: func(cmd *cobra.Command, args []string) {
-		if ecsCluster == "" || taskDefinition == "" {
-			cmd.Usage()
-			os.Exit(1)
-		}
-		sess := session.Must(session.NewSessionWithOptions(session.Options{
-			SharedConfigState: session.SharedConfigEnable,
-		}))
-
-		if taskDefinitionFile {
-			taskDefinition = ParseTaskDefinition(sess, taskDefinition)
-			fmt.Println("Succesfully uploaded: ", taskDefinition)
-		}
-		fmt.Printf("Launching task %s in an ECS Cluster %s...\n", taskDefinition, ecsCluster)
-		logGroupName, logStreamName, taskArnID := RunTask(sess, ecsCluster, launchType, taskDefinition)
-		fmt.Println("Logs:")
-		printEvents(GetLogs(sess, logStreamName, logGroupName))
-		exitCode, exitReason := GetExit(sess, ecsCluster, taskArnID)
-		fmt.Println("Exit reason:", exitReason)
-		os.Exit(int(exitCode))
+		runTask(cmd)
 	},
 }
 
@@ -60,128 +67,957 @@ func Execute() {
 }
 
 func init() {
-	//cobra.OnInitialize(initConfig)
-	rootCmd.Flags().StringVarP(&ecsCluster, "cluster", "c", "", "Name of the Cluster")
-	rootCmd.Flags().StringVarP(&taskDefinition, "task-definition", "t", "", "Task Definition to use can be a json file if used with -f flag")
-	rootCmd.Flags().BoolVarP(&taskDefinitionFile, "file", "f", false, "Read task definition from File")
-	rootCmd.Flags().StringVarP(&launchType, "launch-type", "l", "FARGATE", "Launch Type: allowed EC2 or FARGATE")
-	rootCmd.Flags().StringVarP(&securityGroups, "security-groups", "", "", "Security groups to use")
-	rootCmd.Flags().StringVarP(&subnets, "subnets", "", "", "subnets where to deploy task separated by comma")
+	cobra.OnInitialize(initConfig, initLogging, initCredentials, initEndpoints, initAPIRetries, initHTTP)
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.ecs-run-task.yaml)")
+	rootCmd.PersistentFlags().BoolVar(&noEmoji, "no-emoji", false, "Do not use emoji in output")
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Do not use colors in output")
+	rootCmd.PersistentFlags().BoolVar(&asciiOutput, "ascii", false, "Only use ASCII characters in output")
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Print every status check while waiting")
+	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "Print debug messages, including every AWS API request with its request ID, retries and errors")
+	rootCmd.PersistentFlags().StringVar(&roleArn, "role-arn", "", "Assume this IAM role before calling AWS, e.g. to run tasks in another account")
+	rootCmd.PersistentFlags().StringVar(&externalID, "external-id", "", "External ID to assume --role-arn with")
+	rootCmd.PersistentFlags().StringVar(&roleSessionName, "session-name", "", "Session name to assume --role-arn with (default \""+defaultRoleSessionName+"\")")
+	rootCmd.PersistentFlags().StringArrayVar(&sessionTags, "session-tag", nil, "Session tag to assume --role-arn with, given as KEY=VALUE (repeatable)")
+	rootCmd.PersistentFlags().StringVar(&mfaSerial, "mfa-serial", "", "ARN of the MFA device --role-arn must be assumed with")
+	rootCmd.PersistentFlags().DurationVar(&roleDuration, "role-duration", 0, "How long credentials of roles are valid before they are renewed, between 15m and 12h (default 1h for --role-arn, duration_seconds or 15m for profiles)")
+	rootCmd.PersistentFlags().StringVar(&mfaTokenCode, "mfa-token", "", "MFA token code for profiles or --role-arn requiring MFA, asked for on the terminal if not given")
+	rootCmd.PersistentFlags().StringVar(&endpointURL, "endpoint-url", "", "Send all AWS API requests to this URL, e.g. http://localhost:4566 for LocalStack")
+	rootCmd.PersistentFlags().StringArrayVar(&serviceEndpointFlags, "service-endpoint", nil, "Endpoint URL of a single service, given as SERVICE=URL with the endpoint ID of the service, e.g. ecs, logs, s3, sts or ssm (repeatable)")
+	rootCmd.PersistentFlags().StringVar(&caBundle, "ca-bundle", "", "PEM file with the certificates to trust instead of the system ones, e.g. of a TLS inspecting proxy (default $AWS_CA_BUNDLE)")
+	rootCmd.PersistentFlags().IntVar(&maxAPIRetries, "max-api-retries", defaultMaxAPIRetries, "Retry failed and throttled AWS API requests this many times with backoff")
+	rootCmd.PersistentFlags().StringVar(&apiRetryMode, "api-retry-mode", retryModeStandard, "How to retry AWS API requests: standard, or adaptive to also slow down requests to services that throttled them")
+	rootCmd.PersistentFlags().DurationVar(&heartbeatInterval, "heartbeat", 5*time.Minute, "Print a line with the elapsed time and task status this often while waiting, 0 to disable")
+	rootCmd.PersistentFlags().DurationVar(&pollInterval, "poll-interval", 2*time.Second, "Initial delay between status checks, doubled after every check")
+	rootCmd.PersistentFlags().DurationVar(&maxPollInterval, "max-poll-interval", 30*time.Second, "Maximum delay between status checks")
+	addRunFlags(rootCmd.Flags())
 }
 
-// RunTask runs task definition on specified ECS Cluster
-// It returns the LogStreamName
-func RunTask(sess *session.Session, ecsCluster string, launchType string, taskDefinition string) (string, string, string) {
-	svc := ecs.New(sess)
-	runTaskInput := &ecs.RunTaskInput{
-		Cluster:        aws.String(ecsCluster),
-		Count:          aws.Int64(1),
-		LaunchType:     aws.String(launchType),
-		TaskDefinition: aws.String(taskDefinition),
+// addRunFlags registers the flags that control launching a task. They are
+// shared by every command that launches one.
+func addRunFlags(flags *pflag.FlagSet) {
+	flags.StringVarP(&ecsCluster, "cluster", "c", "", "Name of the Cluster")
+	flags.StringVarP(&taskDefinition, "task-definition", "t", "", "Task Definition to use can be a json file if used with -f flag")
+	flags.BoolVarP(&taskDefinitionFile, "file", "f", false, "Read task definition from File")
+	flags.StringVarP(&launchType, "launch-type", "l", "FARGATE", "Launch Type: allowed EC2 or FARGATE")
+	flags.BoolVarP(&fargateSpot, "fargate-spot", "", false, "Run on Fargate Spot, shortcut for --capacity-provider FARGATE_SPOT")
+	flags.StringArrayVarP(&capacityProviders, "capacity-provider", "", nil, "Launch with a capacity provider strategy instead of --launch-type, given as name[,weight[,base]] (repeatable)")
+	flags.StringVarP(&securityGroups, "security-groups", "", "", "Security groups to use")
+	flags.StringVarP(&subnets, "subnets", "", "", "subnets where to deploy task separated by comma")
+	flags.BoolVarP(&assignPublicIP, "assign-public-ip", "", false, "Give the task a public IP, needed to pull images in public subnets without a NAT gateway")
+	flags.StringVarP(&stdinTo, "stdin-to", "", "", "Upload stdin below this S3 prefix (s3://bucket/prefix) and pass its URI to the task in $"+stdinEnvName)
+	flags.StringArrayVarP(&commandOverrides, "command", "", nil, "Override the command of the first container, or of a container given as container=command (repeatable)")
+	flags.StringVarP(&taskRoleArn, "task-role-arn", "", "", "IAM role the containers of the task run with, instead of the one in the task definition")
+	flags.StringVarP(&executionRoleArn, "execution-role-arn", "", "", "IAM role ECS pulls images, writes logs and reads secrets with, instead of the one in the task definition; required by run-image on Fargate")
+	flags.Int64VarP(&ephemeralStorage, "ephemeral-storage", "", 0, "Ephemeral storage of the Fargate task in GiB, from 21 to 200")
+	flags.StringArrayVarP(&envVars, "env", "e", nil, "Set an environment variable on every container, given as KEY=VALUE, or on one container as container:KEY=VALUE (repeatable)")
+	flags.StringArrayVarP(&envFiles, "env-file", "", nil, "Set environment variables of the task from a dotenv file (repeatable)")
+	flags.StringArrayVarP(&maskEnvs, "mask-env", "", nil, "Mask the value of this environment variable passed to the task in printed logs (repeatable)")
+	flags.StringArrayVarP(&secretEnvs, "secret-env", "", nil, "Set an environment variable of the task to a Secrets Manager secret, given as KEY=secret-arn (repeatable)")
+	flags.StringArrayVarP(&ssmEnvs, "ssm-env", "", nil, "Set an environment variable of the task to an SSM parameter, given as KEY=/parameter/name (repeatable)")
+	flags.StringArrayVarP(&uploads, "upload", "", nil, "Upload a local file before launch, given as path=s3://bucket/key (repeatable)")
+	flags.StringVarP(&fetchArtifactsFrom, "fetch-artifacts", "", "", "Download this S3 prefix after the task stopped, {taskId} is replaced with the ID of the task")
+	flags.StringVarP(&archiveLogsTo, "archive-logs", "", "", "Upload the complete logs and a metadata JSON of the run below this S3 prefix after the task stopped")
+	flags.StringVarP(&artifactsDir, "artifacts-dir", "", "artifacts", "Local directory to download --fetch-artifacts to")
+	flags.StringVarP(&collectResult, "collect-result", "", "", "Collect a JSON result the task writes to the location in $"+resultEnvName+", below s3://bucket/prefix or ssm:/parameter/prefix")
+	flags.StringVarP(&backend, "backend", "", backendECS, "Where to run: ecs, or batch to submit an AWS Batch job")
+	flags.StringVarP(&jobQueue, "job-queue", "", "", "AWS Batch job queue to submit to with --backend batch")
+	flags.StringVarP(&jobDefinition, "job-definition", "", "", "AWS Batch job definition to submit with --backend batch")
+	flags.BoolVarP(&createLogGroup, "create-log-group", "", false, "Create the log groups of the task definition that do not exist yet before launching the task")
+	flags.Int64VarP(&logRetentionDays, "log-retention-days", "", 0, "Keep the logs of log groups created with --create-log-group this many days, 0 to keep them forever")
+	flags.StringVarP(&logTime, "log-time", "", logTimeEvent, "Time to print and order log lines by: event (set by the container) or ingestion (set by CloudWatch)")
+	addTimestampsFlag(flags)
+	flags.DurationVarP(&logStreamWait, "log-stream-wait", "", 30*time.Second, "How long to wait for a log stream that does not exist yet before giving up on its logs")
+	flags.DurationVarP(&logsSince, "since", "", 0, "Only print log events of this long ago or later, e.g. 10m")
+	flags.IntVarP(&logTail, "tail", "", 0, "Only print the last this many log events of each stream, 0 for all")
+	flags.StringVarP(&logGrep, "grep", "", "", "Only print log lines matching this regular expression")
+	flags.StringVarP(&logGrepV, "grep-v", "", "", "Do not print log lines matching this regular expression")
+	flags.StringVarP(&logFile, "log-file", "", "", "Also write the printed log lines to this file")
+	flags.BoolVarP(&logFilePrefixes, "log-file-prefixes", "", false, "Write log lines to --log-file with their timestamp and container prefix")
+	flags.StringArrayVarP(&maskFlags, "mask", "", nil, "Replace this value, or regular expression given as /regex/, with *** in printed logs (repeatable)")
+	flags.StringVarP(&logFilterPattern, "filter-pattern", "", "", "Only fetch log events matching this CloudWatch Logs filter pattern")
+	flags.BoolVarP(&filterLogs, "filter-logs", "", false, "Fetch log events with FilterLogEvents from every log stream whose name starts with the name of a container's stream")
+	flags.BoolVarP(&followLogs, "follow", "", false, "Print logs while the task is running instead of after it stopped")
+	flags.BoolVarP(&useLiveTail, "live-tail", "", false, "With --follow, have CloudWatch Logs Live Tail push new log lines instead of polling for them")
+	flags.StringVarP(&failOnLogPattern, "fail-on-log-pattern", "", "", "Fail the run if a log line matches this regular expression, even if the task exits with 0")
+	flags.BoolVarP(&stopOnLogPattern, "stop-on-log-pattern", "", false, "Stop the task as soon as a log line matches --fail-on-log-pattern, needs --follow")
+	flags.IntVarP(&maxLogEvents, "max-log-events", "", 0, "Print at most this many log events per stream, 0 for all")
+	flags.IntVarP(&logConcurrency, "log-concurrency", "", 4, "How many log streams to fetch at a time")
+	flags.StringVarP(&cpuArchitecture, "cpu-architecture", "", "", "CPU architecture to run on, X86_64 or ARM64; set on task definitions registered by this tool, checked on others")
+	flags.StringVarP(&osFamily, "os-family", "", "", "Operating system family to run on, e.g. LINUX or WINDOWS_SERVER_2022_CORE; set on task definitions registered by this tool, checked on others")
+	flags.DurationVarP(&maxRunTime, "max-run-time", "", 0, fmt.Sprintf("Stop the task and exit with code %d if it runs longer than this, 0 for no limit", exitCodeTimeout))
+	flags.StringVarP(&runOutput, "output", "o", "text", "Output format: text, or json to print a JSON result to stdout and everything else to stderr")
+	flags.StringVarP(&progressFormat, "progress", "", "", "Emit progress events: json to print them to stdout as NDJSON and everything else to stderr")
+	flags.StringVarP(&exitCodeFrom, "exit-code-from", "", exitCodeFromEssential, "Exit with the code of: essential (the first failed essential container), max (the highest of all containers) or a container name")
+	flags.IntVarP(&retries, "retries", "", 0, "Run the task again up to this many times if it fails, with exponential backoff (see --poll-interval)")
+	flags.StringVarP(&retryOn, "retry-on", "", retryOnFailure, "Failures --retries applies to: failure (any) or infrastructure (out of memory, image pull, Spot interruption, failed to start)")
+	flags.StringVarP(&startedByValue, "started-by", "", "", "startedBy of the task, at most 36 characters (default ecs-run-task/<user>)")
+	flags.StringVarP(&taskGroup, "group", "", "", "Task group of the task (default family:<task definition family>)")
+	flags.StringVarP(&referenceID, "reference-id", "", "", "Reference ID of the task, e.g. a CI build ID")
+	flags.BoolVarP(&enableExecuteCommand, "enable-execute-command", "", false, "Enable ECS Exec, so commands can be run in the task with the exec subcommand")
+	flags.StringArrayVarP(&taskTags, "tag", "", nil, "Tag the task, given as KEY=VALUE (repeatable)")
+	flags.StringVarP(&propagateTags, "propagate-tags", "", "", "Copy the tags of the TASK_DEFINITION or SERVICE to the task")
+	flags.BoolVarP(&showTimeline, "timeline", "", false, "Print status changes of the task while waiting and when it reached each stage, e.g. pulling images, once it stopped")
+	flags.StringVarP(&waitUntil, "wait-until", "", waitUntilStopped, "Wait until the task is stopped, or only until it is running or healthy and exit with 0, leaving it running")
+	flags.StringVarP(&taskArnFile, "task-arn-file", "", "", "Write the ARN of the task to this file right after launch, one per line with --count")
+	flags.BoolVarP(&noWait, "no-wait", "", false, "Exit right after launching the task, printing its ARN and log stream")
+	flags.BoolVarP(&stopOnInterrupt, "stop-on-interrupt", "", false, "Stop the task when interrupted with Ctrl-C or SIGTERM instead of asking, or leaving it running without a terminal")
+	flags.VarP(waitTimeoutValue{&waitTimeout}, "wait-timeout", "", "How long to wait for the task to stop, or unlimited")
+	flags.IntVarP(&placementRetries, "placement-retries", "", 0, "Retry launching this many times with backoff when ECS cannot place the task, e.g. for lack of memory or capacity")
+	flags.IntVarP(&taskCount, "count", "", 1, "Number of copies of the task to launch; the run fails if any of them fails")
+	flags.StringArrayVarP(&placementConstraints, "placement-constraint", "", nil, "Place the EC2 task on a container instance matching a cluster query expression, e.g. \"attribute:ecs.instance-type =~ c5.*\", or on its own instance with distinctInstance (repeatable)")
+	flags.StringArrayVarP(&placementStrategies, "placement-strategy", "", nil, "Place EC2 tasks with a strategy, e.g. spread=attribute:ecs.availability-zone, binpack=memory or random (repeatable, applied in order)")
+	flags.StringVarP(&platformVersion, "platform-version", "", "", "Fargate platform version to run on")
+	flags.BoolVarP(&strictMode, "strict", "", false, "Fail instead of warning when the task definition uses deprecated features or misses best practices")
+	flags.BoolVarP(&checkQuotas, "check-quotas", "", false, "Look up the current limit and usage when a run fails because of a service quota")
+	flags.BoolVarP(&logSummary, "log-summary", "", false, "Print how many log lines mention errors and warnings, counted with CloudWatch Logs Insights, after the task stopped")
+	flags.StringVarP(&logQuery, "log-query", "", "", "Print the results of this CloudWatch Logs Insights query over the logs of the task after it stopped, instead of the --log-summary counts")
+	flags.BoolVarP(&usageSummary, "usage-summary", "", false, "Print CPU and memory utilization of the task from Container Insights after it stopped")
+	flags.BoolVarP(&enableInsights, "enable-insights", "", false, "Enable Container Insights with enhanced observability on the cluster if it is not enabled")
+	flags.StringVarP(&lockTable, "lock-table", "", "", "Hold a lock in this DynamoDB table while the task runs, so only one run per cluster and task family happens at a time")
+	flags.StringVarP(&auditLog, "audit-log", "", "", "Append an audit record of the run to this file")
+	flags.BoolVarP(&noHistory, "no-history", "", false, "Do not record the run in the local run history")
+	flags.BoolVarP(&trackDuration, "track-duration", "", false, "Compare the duration of successful runs against earlier runs and warn about slowdowns")
+	flags.StringVarP(&durationHistory, "duration-history", "", defaultDurationHistory(), "File --track-duration keeps recent durations in")
+	flags.IntVarP(&slowdownThreshold, "slowdown-threshold", "", 50, "Percentage a run may take longer than the baseline before --track-duration warns")
+	flags.BoolVarP(&failOnSlowdown, "fail-on-slowdown", "", false, "Fail instead of warning when --track-duration detects a slowdown")
+	flags.StringVarP(&fanOutRegions, "regions", "", "", "Run the task in each of these comma separated regions in parallel and print a combined result")
+	flags.StringArrayVarP(&failoverTargets, "failover", "", nil, "When the launch fails for lack of capacity, launch in REGION:CLUSTER[:SUBNETS[:SECURITY-GROUPS]] instead (repeatable, tried in order)")
+	flags.BoolVarP(&assumeYes, "yes", "y", false, "Do not ask for confirmation when running in a protected cluster")
+}
+
+// validateRunFlags exits if a run flag has an invalid value, before
+// anything is registered or launched.
+func validateRunFlags() {
+	validateLogFlags()
+	validatePlatformFlags()
+	validateOutputFlags()
+	validateRetryFlags()
+	validateWaitUntil()
+	validateLogPatternFlags()
+	validateLogGroupFlags()
+}
+
+// runTask launches the task described by the run flags, prints its logs and
+// exits with the exit code of the task.
+func runTask(cmd *cobra.Command) {
+	validateRunFlags()
+	redirectOutput()
+	openLogFile()
+	// cluster and definition identify what is run for locks and audit records.
+	cluster, definition := ecsCluster, taskDefinition
+	switch backend {
+	case backendECS:
+		if ecsCluster == "" || taskDefinition == "" {
+			cmd.Usage()
+			exit(1)
+		}
+		if (fargateSpot || len(capacityProviders) > 0) && cmd.Flags().Changed("launch-type") {
+			fmt.Println("--launch-type cannot be used with --fargate-spot or --capacity-provider")
+			exit(1)
+		}
+		confirmProtectedCluster(ecsCluster)
+	case backendBatch:
+		if jobQueue == "" || jobDefinition == "" {
+			cmd.Usage()
+			exit(1)
+		}
+		cluster, definition = jobQueue, jobDefinition
+	default:
+		fmt.Println("Unknown backend:", backend)
+		exit(1)
 	}
-	if subnets != "" || securityGroups != "" {
-		fmt.Println("test")
-		runTaskInput.NetworkConfiguration = &ecs.NetworkConfiguration{
-			AwsvpcConfiguration: &ecs.AwsVpcConfiguration{
-				Subnets:        aws.StringSlice(strings.Split(subnets, ",")),
-				SecurityGroups: aws.StringSlice(strings.Split(securityGroups, ",")),
-			},
+	if fanOutRegions != "" {
+		if resultOutput != nil || progressOutput != nil {
+			fmt.Println("--output json and --progress json cannot be used with --regions")
+			exit(1)
 		}
+		runInRegions()
+	}
+	ctx := cmd.Context()
+	cfg := newConfig(ctx)
+
+	var definitionFile string
+	if taskDefinitionFile && backend == backendECS {
+		definitionFile = taskDefinition
+		taskDefinition = ParseTaskDefinition(ctx, cfg, definitionFile)
+		fmt.Println("Succesfully uploaded: ", taskDefinition)
+		// Locks and audit records are keyed on the family, not the file.
+		definition = taskDefinition
+	}
+	for _, envFile := range envFiles {
+		vars, err := readEnvFile(envFile)
+		if err != nil {
+			exitWithError("Got error reading env file:", err)
+		}
+		for _, v := range vars {
+			addEnvOverride(v[0], v[1])
+		}
+	}
+	for _, envVar := range envVars {
+		addEnvVar(envVar)
+	}
+	if backend == backendBatch && len(containerEnvOverrides) > 0 {
+		fmt.Println("--env container:KEY=VALUE cannot be used with --backend batch")
+		exit(1)
+	}
+	if ephemeralStorage != 0 && (ephemeralStorage < 21 || ephemeralStorage > 200) {
+		fmt.Printf("Invalid --ephemeral-storage %d, expected 21 to 200 GiB\n", ephemeralStorage)
+		exit(1)
+	}
+	if noWait && (backend == backendBatch || lockTable != "" || fetchArtifactsFrom != "" || collectResult != "" ||
+		trackDuration || usageSummary || followLogs || maxRunTime > 0 || retries > 0 || failOnLogPattern != "" || archiveLogsTo != "" || runLogQuery() != "") {
+		fmt.Println("--no-wait cannot be used with --backend batch, --lock-table, --fetch-artifacts, --collect-result, --track-duration, --usage-summary, --follow, --max-run-time, --retries, --fail-on-log-pattern, --archive-logs, --log-summary or --log-query")
+		exit(1)
+	}
+	if waitUntil != waitUntilStopped && (noWait || backend == backendBatch || lockTable != "" || fetchArtifactsFrom != "" || collectResult != "" ||
+		trackDuration || usageSummary || followLogs || maxRunTime > 0 || retries > 0 || failOnLogPattern != "" || archiveLogsTo != "" || runLogQuery() != "") {
+		fmt.Println("--wait-until running or healthy cannot be used with --no-wait, --backend batch, --lock-table, --fetch-artifacts, --collect-result, --track-duration, --usage-summary, --follow, --max-run-time, --retries, --fail-on-log-pattern, --archive-logs, --log-summary or --log-query")
+		exit(1)
+	}
+	if retries > 0 && backend == backendBatch {
+		fmt.Println("--retries cannot be used with --backend batch, use the retry strategy of the job definition")
+		exit(1)
+	}
+	if archiveLogsTo != "" {
+		if _, _, err := parseS3URI(archiveLogsTo); err != nil {
+			fmt.Printf("Invalid --archive-logs: %s\n", err)
+			exit(1)
+		}
+	}
+	if createLogGroup && backend == backendBatch {
+		fmt.Println("--create-log-group cannot be used with --backend batch")
+		exit(1)
+	}
+	if stopOnLogPattern && backend == backendBatch {
+		fmt.Println("--stop-on-log-pattern cannot be used with --backend batch")
+		exit(1)
+	}
+	validateTagFlags()
+	validateCapacityFlags()
+	validateFailoverFlags()
+	validateCommandFlags()
+	validateBatchFlags()
+	validateUploadFlags()
+	validatePlacementFlags()
+	if backend == backendBatch && (len(taskTags) > 0 || propagateTags != "") {
+		fmt.Println("--tag and --propagate-tags cannot be used with --backend batch")
+		exit(1)
+	}
+	if len(startedByValue) > 36 {
+		fmt.Printf("Invalid --started-by %q, ECS allows at most 36 characters\n", startedByValue)
+		exit(1)
+	}
+	if taskCount < 1 {
+		fmt.Printf("Invalid --count %d, expected at least 1\n", taskCount)
+		exit(1)
+	}
+	if taskCount > 1 && (backend == backendBatch || collectResult != "") {
+		fmt.Println("--count cannot be used with --backend batch or --collect-result")
+		exit(1)
+	}
+	if backend == backendBatch && (taskRoleArn != "" || executionRoleArn != "" || ephemeralStorage != 0) {
+		fmt.Println("--task-role-arn, --execution-role-arn and --ephemeral-storage cannot be used with --backend batch")
+		exit(1)
+	}
+	for _, secretEnv := range secretEnvs {
+		name, ref := splitKeyValue("secret-env", secretEnv)
+		value, err := resolveSecret(ctx, cfg, ref)
+		if err != nil {
+			exitWithError("Got error resolving secret for "+name+":", err)
+		}
+		addMask(value)
+		addEnvOverride(name, value)
+	}
+	for _, ssmEnv := range ssmEnvs {
+		name, parameter := splitKeyValue("ssm-env", ssmEnv)
+		value, err := resolveParameter(ctx, cfg, parameter)
+		if err != nil {
+			exitWithError("Got error resolving parameter for "+name+":", err)
+		}
+		addMask(value)
+		addEnvOverride(name, value)
+	}
+	maskEnvOverrides()
+	for _, upload := range uploads {
+		name, uri := uploadFile(ctx, cfg, upload)
+		fmt.Printf("Passing %s to the task in $%s\n", uri, name)
+		addEnvOverride(name, uri)
+	}
+	if stdinTo != "" {
+		addEnvOverride(stdinEnvName, uploadStdin(ctx, cfg, stdinTo))
+	}
+	var resultAt string
+	if collectResult != "" {
+		resultAt = resultLocation(collectResult)
+		addEnvOverride(resultEnvName, resultAt)
+	}
+	if lockTable != "" {
+		key := lockKey(cluster, definition)
+		ttl := 12 * time.Hour
+		if maxRunTime > 0 {
+			ttl = time.Duration(retries+1)*maxRunTime + 15*time.Minute
+		}
+		if err := acquireLock(ctx, cfg, lockTable, key, ttl); err != nil {
+			exitWithError("Got error acquiring lock:", err)
+		}
+		fmt.Println("Acquired lock", key)
+		releaseLockOnExit(ctx, cfg, lockTable, key, lockOwner())
+	}
+	state := &runState{
+		Owner:          lockOwner(),
+		Cluster:        cluster,
+		TaskDefinition: definition,
+		StartedAt:      time.Now(),
+		MaxRunTime:     maxRunTime,
+		WaitTimeout:    waitTimeout,
+		UsageSummary:   usageSummary,
+		Follow:         followLogs,
+		FetchArtifacts: fetchArtifactsFrom,
+		ArtifactsDir:   artifactsDir,
+		ResultAt:       resultAt,
+		AuditLog:       auditLog,
+		ArchiveLogs:    archiveLogsTo,
+		LogQuery:       runLogQuery(),
+		ExitCodeFrom:   exitCodeFrom,
+		Timeline:       showTimeline,
+	}
+	state.FailOnLogPattern, state.StopOnLogPattern = failOnLogPattern, stopOnLogPattern
+	if !noHistory {
+		state.History = newHistoryEntry(cluster, definition)
+	}
+	if trackDuration {
+		state.DurationHistory, state.SlowdownThreshold, state.FailOnSlowdown = durationHistory, slowdownThreshold, failOnSlowdown
+	}
+	if lockTable != "" {
+		state.LockTable, state.LockKey, state.LockOwner = lockTable, lockKey(cluster, definition), lockOwner()
+		state.LockRegion = cfg.Region
+	}
+	auditOnExit(state)
+	if backend == backendBatch {
+		fmt.Printf("Submitting job %s to job queue %s...\n", jobDefinition, jobQueue)
+		var exitCode int64
+		var exitReason string
+		var logGroupName, logStreamName string
+		logGroupName, logStreamName, state.TaskArn, exitCode, exitReason = RunBatchJob(ctx, cfg, jobQueue, jobDefinition)
+		category := classifyStop("", exitReason)
+		if category == stopCategoryUnknown {
+			category = stopCategoryEssentialExited
+		}
+		exitCode = runExitCode(exitCode, category)
+		watchLogPattern(ctx, cfg, state)
+		if logStreamName != "" {
+			state.LogStreams = []logStream{{Group: logGroupName, Stream: logStreamName}}
+			fmt.Println("Logs:")
+			printLogs(ctx, cfg, logStreamName, logGroupName)
+		}
+		exitCode, exitReason = logPatternExit(exitCode, exitReason)
+		completeRun(ctx, cfg, state, nil, exitCode, exitReason, category)
+	}
+	launchCfg := cfg
+	for attempt := 1; ; attempt++ {
+		if retries > 0 {
+			fmt.Printf("Attempt %d of %d\n", attempt, retries+1)
+		}
+		var taskArns []string
+		var err error
+		cfg, state.Region, state.Cluster, taskArns, state.LogStreams, err = launchWithFailover(ctx, launchCfg, definitionFile)
+		if err != nil {
+			exitWithError("Got error launching task:", err)
+		}
+		state.StartedAt = time.Now()
+		state.TaskArn, state.TaskArns = taskArns[0], nil
+		if len(taskArns) > 1 {
+			state.TaskArns = taskArns
+		}
+		for _, taskArn := range taskArns {
+			fmt.Println("Launched task", taskArn)
+			fmt.Println("Console:", consoleURL(cfg.Region, state.Cluster, taskArn))
+			emitProgress(progressEvent{Event: progressTaskLaunched, TaskArn: taskArn, Cluster: state.Cluster, Region: state.Region})
+		}
+		if taskArnFile != "" {
+			if err := ioutil.WriteFile(taskArnFile, []byte(strings.Join(taskArns, "\n")+"\n"), 0644); err != nil {
+				printError("Got error writing --task-arn-file:", err)
+			}
+		}
+		if noWait {
+			for _, s := range state.LogStreams {
+				fmt.Printf("Log stream %s in log group %s\n", s.Stream, s.Group)
+			}
+			recordHistory(state, nil, "", "")
+			printRunResult(newRunResult(state))
+			exit(0)
+		}
+		if err := saveState(state); err != nil {
+			printError("Got error saving state, the run cannot be resumed:", err)
+		}
+		if waitUntil != waitUntilStopped {
+			waitForStart(ctx, cfg, state)
+		}
+		tasks, result := waitForRun(ctx, cfg, state)
+		if attempt > retries || !shouldRetry(result) {
+			completeRun(ctx, cfg, state, tasks, result.exitCode, result.exitReason, result.category)
+		}
+		removeState(state)
+		delay := pollDelay(attempt)
+		printStatus(false, fmt.Sprintf("attempt %d exited with code %d (%s), retrying in %s", attempt, result.exitCode, result.category, delay.Round(time.Second)))
+		time.Sleep(delay)
+	}
+}
+
+// monitorTask waits for the launched ECS tasks to stop, prints their logs
+// and exits with their exit code.
+func monitorTask(ctx context.Context, cfg aws.Config, state *runState) {
+	tasks, result := waitForRun(ctx, cfg, state)
+	completeRun(ctx, cfg, state, tasks, result.exitCode, result.exitReason, result.category)
+}
+
+// waitForRun waits for the launched ECS tasks to stop and prints their
+// logs. It returns the stopped tasks and how the run ended: like the first
+// failed task, or else the first task.
+func waitForRun(ctx context.Context, cfg aws.Config, state *runState) ([]*ecstypes.Task, taskExit) {
+	maxRunTime, waitTimeout, showTimeline = state.MaxRunTime, state.WaitTimeout, state.Timeline
+	svc := ecs.NewFromConfig(cfg)
+	stopHandlingInterrupts := handleInterrupts(ctx, svc, state)
+	onTaskRunning = addressPrinter(ctx, cfg)
+	watchLogPattern(ctx, cfg, state)
+	stopHeartbeat := startWaitDisplay(state)
+	if state.Follow {
+		fmt.Println("Logs:")
+		stop := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			followStreams(ctx, cfg, state.LogStreams, stop)
+			close(done)
+		}()
+		err := waitForStops(ctx, svc, state.Cluster, state.tasks(), state.StartedAt)
+		stopHeartbeat()
+		close(stop)
+		<-done
+		if err != nil {
+			exitWithError("Got error running the task:", err)
+		}
+	} else {
+		err := waitForStops(ctx, svc, state.Cluster, state.tasks(), state.StartedAt)
+		stopHeartbeat()
+		if err != nil {
+			exitWithError("Got error running the task:", err)
+		}
+		fmt.Println("Logs:")
+		printLogStreams(ctx, cfg, state.LogStreams)
+	}
+	stopHandlingInterrupts()
+	var exits []taskExit
+	for _, taskArn := range state.tasks() {
+		exitCode, exitReason, task := GetExit(ctx, cfg, state.Cluster, taskArn, state.ExitCodeFrom)
+		category := classifyTask(task)
+		exitCode = runExitCode(exitCode, category)
+		exitCode, exitReason = logPatternExit(exitCode, exitReason)
+		emitProgress(progressEvent{
+			Event:        progressTaskStopped,
+			TaskArn:      taskArn,
+			ExitCode:     aws.Int64(exitCode),
+			ExitReason:   exitReason,
+			StopCategory: category,
+		})
+		if category == stopCategoryOOMKilled {
+			reportOOM(ctx, cfg, task)
+		}
+		if state.UsageSummary {
+			printUsageSummary(ctx, cfg, task)
+		}
+		printContainerExits(task, len(state.tasks()) > 1)
+		if state.Timeline {
+			printTaskTimeline(task)
+		}
+		exits = append(exits, taskExit{taskArn, exitCode, exitReason, category, task})
+	}
+	if len(exits) > 1 {
+		printTaskExits(exits)
+	}
+	// The run fails with the first failed task.
+	result := exits[0]
+	var tasks []*ecstypes.Task
+	for _, e := range exits {
+		if e.exitCode != 0 && result.exitCode == 0 {
+			result = e
+		}
+		tasks = append(tasks, e.task)
+	}
+	return tasks, result
+}
+
+// taskExit is how a task of a run stopped.
+type taskExit struct {
+	taskArn    string
+	exitCode   int64
+	exitReason string
+	category   stopCategory
+	task       *ecstypes.Task
+}
+
+// printTaskExits prints a table of how the tasks of a run stopped.
+func printTaskExits(exits []taskExit) {
+	fmt.Println("Tasks:")
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "TASK\tEXIT CODE\tSTOP CATEGORY\tEXIT REASON")
+	for _, e := range exits {
+		fmt.Fprintf(table, "%s\t%d\t%s\t%s\n", taskID(e.taskArn), e.exitCode, e.category, e.exitReason)
+	}
+	table.Flush()
+}
+
+// printContainerExits prints a table of how every container of task exited,
+// so failures of sidecars are visible too. ECS only reports how long the
+// task ran, not each of its containers.
+func printContainerExits(task *ecstypes.Task, several bool) {
+	ran := "never started"
+	if task.StartedAt != nil && task.StoppedAt != nil {
+		ran = "ran " + task.StoppedAt.Sub(*task.StartedAt).Round(time.Second).String()
+	}
+	if several {
+		fmt.Printf("Containers of task %s (%s):\n", taskID(aws.ToString(task.TaskArn)), ran)
+	} else {
+		fmt.Printf("Containers (%s):\n", ran)
+	}
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "NAME\tEXIT CODE\tREASON")
+	for _, c := range task.Containers {
+		exitCode := fmt.Sprint(containerExitCode(c))
+		reason := aws.ToString(c.Reason)
+		if c.ExitCode == nil {
+			exitCode += " (none reported)"
+			if reason == "" {
+				reason = "container did not run"
+			}
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\n", aws.ToString(c.Name), exitCode, reason)
+	}
+	table.Flush()
+}
+
+// completeRun reports the outcome of a run, collects its artifacts and
+// result and exits with the exit code of the task. tasks are the stopped
+// ECS tasks of the run, nil for Batch jobs.
+func completeRun(ctx context.Context, cfg aws.Config, state *runState, tasks []*ecstypes.Task, exitCode int64, exitReason string, category stopCategory) {
+	output := newRunResult(state)
+	var taskIDs []string
+	for _, taskArn := range state.tasks() {
+		taskIDs = append(taskIDs, taskID(taskArn))
 	}
-	output, err := svc.RunTask(runTaskInput)
+	fmt.Println("Exit reason:", exitReason)
+	fmt.Println("Stop category:", category)
+	printHints(exitReason)
+	if state.LogQuery != "" {
+		if err := printLogSummary(ctx, cfg, state); err != nil {
+			printError("Got error summarizing logs:", err)
+		}
+	}
+	for _, id := range taskIDs {
+		if state.FetchArtifacts == "" {
+			break
+		}
+		dir := state.ArtifactsDir
+		if len(taskIDs) > 1 {
+			dir = filepath.Join(dir, id)
+		}
+		if err := fetchArtifacts(ctx, cfg, state.FetchArtifacts, id, dir); err != nil {
+			printError("Got error fetching artifacts:", err)
+			if exitCode == 0 {
+				exitCode = 1
+			}
+		}
+	}
+	if state.ResultAt != "" {
+		result, err := readResult(ctx, cfg, state.ResultAt)
+		if err != nil {
+			printError("Got error collecting result:", err)
+			if exitCode == 0 {
+				exitCode = 1
+			}
+		} else {
+			fmt.Println("Result:")
+			fmt.Println(string(result))
+			output.Result = string(result)
+		}
+	}
+	if strings.HasPrefix(exitReason, timeoutStopReason) {
+		exitCode = exitCodeTimeout
+	}
+	if strings.HasPrefix(exitReason, interruptStopReason) {
+		exitCode = exitCodeInterrupted
+	}
+	if state.DurationHistory != "" && exitCode == 0 {
+		key := lockKey(state.Cluster, state.TaskDefinition)
+		slow, err := checkDuration(state.DurationHistory, key, time.Since(state.StartedAt), state.SlowdownThreshold)
+		if err != nil {
+			printError("Got error tracking duration:", err)
+		}
+		if slow && state.FailOnSlowdown {
+			exitCode = 1
+		}
+	}
+	// The record is written by auditOnExit.
+	auditOutcome = &auditRecord{ExitCode: exitCode, ExitReason: exitReason, StopCategory: category}
+	recordHistory(state, &exitCode, exitReason, category)
+	output.ExitCode, output.ExitReason, output.StopCategory = &exitCode, exitReason, category
+	output.Duration = time.Since(state.StartedAt).Round(time.Second).String()
+	for _, task := range tasks {
+		output.Tasks = append(output.Tasks, newTaskResult(task))
+	}
+	if state.ArchiveLogs != "" {
+		if err := archiveLogs(ctx, cfg, state, output); err != nil {
+			printError("Got error archiving logs:", err)
+		}
+	}
+	printRunResult(output)
+	removeState(state)
+	printStatus(exitCode == 0, fmt.Sprintf("task exited with code %d", exitCode))
+	exit(int(exitCode))
+}
+
+// recordHistory appends the run of state to the run history, if it is kept.
+// exitCode is nil for runs that did not wait for the task.
+func recordHistory(state *runState, exitCode *int64, exitReason string, category stopCategory) {
+	if state.History == nil {
+		return
+	}
+	entry := *state.History
+	entry.Region = state.Region
+	entry.Cluster = state.Cluster
+	entry.TaskArns = state.tasks()
+	if exitCode != nil {
+		entry.Duration = time.Since(state.StartedAt).Round(time.Second).String()
+		entry.ExitCode, entry.ExitReason, entry.StopCategory = exitCode, exitReason, category
+	}
+	if err := appendHistory(&entry); err != nil {
+		printError("Got error writing run history:", err)
+	}
+}
+
+// newConfig loads the AWS configuration from the environment and shared
+// config.
+func newConfig(ctx context.Context) aws.Config {
+	return awsConfig(ctx, "")
+}
+
+// newRegionConfig is newConfig in the given region, or the configured one
+// if region is empty.
+func newRegionConfig(ctx context.Context, region string) aws.Config {
+	return awsConfig(ctx, region)
+}
+
+// justRegistered is set when the task definition was registered by this
+// run and may not be visible to every ECS API yet.
+var justRegistered bool
+
+// retryRegistered calls f, retrying for a few seconds while ECS reports a
+// task definition registered by this run as missing or inactive.
+func retryRegistered(f func() error) error {
+	err := f()
+	for attempt := 1; err != nil && justRegistered && isNotYetVisible(err) && attempt <= 5; attempt++ {
+		warnf("task definition is not visible yet, retrying...")
+		time.Sleep(time.Duration(attempt) * time.Second)
+		err = f()
+	}
+	return err
+}
+
+// isNotYetVisible reports whether err is how ECS fails for a task definition
+// that was registered moments ago.
+func isNotYetVisible(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "taskdefinition not found") ||
+		strings.Contains(msg, "task definition not found") ||
+		strings.Contains(msg, "taskdefinition is inactive") ||
+		strings.Contains(msg, "unable to describe task definition")
+}
+
+// RunTask runs task definition on specified ECS Cluster
+// It returns the task ARNs and the log streams of their containers
+func RunTask(ctx context.Context, cfg aws.Config, ecsCluster string, launchType string, taskDefinition string) ([]string, []logStream) {
+	target := launchTarget{cfg.Region, ecsCluster, subnets, securityGroups}
+	taskArns, streams, err := launchTask(ctx, cfg, target, launchType, taskDefinition)
 	if err != nil {
-		fmt.Println("Got error launching task:")
-		fmt.Println(err.Error())
-		os.Exit(1)
+		exitWithError("Got error launching task:", err)
 	}
+	return taskArns, streams
+}
 
-	taskArn := *output.Tasks[0].TaskArn
-	taskArnSplit := strings.Split(taskArn, "/")
-	taskArnID := taskArnSplit[len(taskArnSplit)-1]
+// maxRunTaskCount is the most tasks a single RunTask call launches.
+const maxRunTaskCount = 10
 
-	containerName := *output.Tasks[0].Containers[0].Name
+// consoleURL returns the link to taskArn in the ECS console.
+func consoleURL(region string, cluster string, taskArn string) string {
+	return fmt.Sprintf("https://%s.console.aws.amazon.com/ecs/v2/clusters/%s/tasks/%s/configuration?region=%s",
+		region, clusterName(cluster), taskID(taskArn), region)
+}
 
-	taskDefinitionOutput, _ := svc.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{
+// launchTask is RunTask returning the error when ECS does not launch the
+// tasks in the cluster and network of target. taskCount tasks are launched;
+// if not all of them can be, the ones that were are stopped again.
+func launchTask(ctx context.Context, cfg aws.Config, target launchTarget, launchType string, taskDefinition string) ([]string, []logStream, error) {
+	ecsCluster := target.cluster
+	svc := ecs.NewFromConfig(cfg)
+	runTaskInput := &ecs.RunTaskInput{
+		Cluster:        aws.String(ecsCluster),
+		LaunchType:     ecstypes.LaunchType(launchType),
 		TaskDefinition: aws.String(taskDefinition),
-	})
-	logPrefix := *taskDefinitionOutput.TaskDefinition.ContainerDefinitions[0].LogConfiguration.Options["awslogs-stream-prefix"]
-	err = svc.WaitUntilTasksStopped(&ecs.DescribeTasksInput{
-		Cluster: aws.String(ecsCluster),
-		Tasks:   aws.StringSlice([]string{taskArn}),
+		StartedBy:      aws.String(startedBy()),
+	}
+	if taskGroup != "" {
+		runTaskInput.Group = aws.String(taskGroup)
+	}
+	if referenceID != "" {
+		runTaskInput.ReferenceId = aws.String(referenceID)
+	}
+	if enableExecuteCommand {
+		runTaskInput.EnableExecuteCommand = true
+	}
+	runTaskInput.Tags = resourceTags()
+	if propagateTags != "" {
+		runTaskInput.PropagateTags = ecstypes.PropagateTags(propagateTags)
+	}
+	var taskDefinitionOutput *ecs.DescribeTaskDefinitionOutput
+	err := retryRegistered(func() (err error) {
+		taskDefinitionOutput, err = svc.DescribeTaskDefinition(ctx, &ecs.DescribeTaskDefinitionInput{
+			TaskDefinition: aws.String(taskDefinition),
+		})
+		return err
 	})
 	if err != nil {
-		fmt.Println("Got error running the task:")
-		fmt.Println(err.Error())
-		os.Exit(1)
+		exitWithError("Got error describing task definition:", err)
+	}
+	lintTaskDefinition(taskDefinitionOutput.TaskDefinition)
+	checkRuntimePlatform(taskDefinitionOutput.TaskDefinition)
+	validateExitCodeFrom(taskDefinitionOutput.TaskDefinition)
+	checkHealthCheck(taskDefinitionOutput.TaskDefinition)
+	if createLogGroup {
+		createLogGroups(ctx, cfg, taskDefinitionOutput.TaskDefinition)
+	}
+	if platformVersion != "" {
+		runTaskInput.PlatformVersion = aws.String(platformVersion)
+	}
+	runTaskInput.Overrides = taskOverride(taskDefinitionOutput.TaskDefinition)
+	if strategy := capacityProviderStrategy(); strategy != nil {
+		runTaskInput.LaunchType = ""
+		runTaskInput.CapacityProviderStrategy = strategy
+	}
+	runTaskInput.NetworkConfiguration = networkConfiguration(target.subnets, target.securityGroups)
+	runTaskInput.PlacementConstraints = taskPlacementConstraints()
+	runTaskInput.PlacementStrategy = taskPlacementStrategy()
+	var taskArns []string
+	retries := 0
+	for remaining := maxInt(taskCount, 1); remaining > 0; {
+		runTaskInput.Count = aws.Int32(int32(minInt(remaining, maxRunTaskCount)))
+		var output *ecs.RunTaskOutput
+		err = retryRegistered(func() (err error) {
+			output, err = svc.RunTask(ctx, runTaskInput)
+			return err
+		})
+		if err != nil {
+			stopTasks(ctx, svc, ecsCluster, taskArns, "ecs-run-task: not all tasks could be launched")
+			return nil, nil, err
+		}
+		for _, task := range output.Tasks {
+			taskArns = append(taskArns, aws.ToString(task.TaskArn))
+		}
+		remaining -= len(output.Tasks)
+		if len(output.Failures) == 0 {
+			continue
+		}
+		// Failures are tasks ECS could not place, e.g. for lack of
+		// resources. Placement may succeed once capacity frees up.
+		var reasons []string
+		for _, failure := range output.Failures {
+			reasons = append(reasons, strings.TrimSpace(aws.ToString(failure.Reason)+" "+aws.ToString(failure.Detail)))
+		}
+		if retries < placementRetries {
+			delay := pollDelay(retries)
+			retries++
+			fmt.Printf("Could not place %d tasks (%s), retrying in %s...\n", len(output.Failures), strings.Join(reasons, "; "), delay.Round(time.Second))
+			time.Sleep(delay)
+			continue
+		}
+		stopTasks(ctx, svc, ecsCluster, taskArns, "ecs-run-task: not all tasks could be launched")
+		if len(taskArns) > 0 {
+			return nil, nil, fmt.Errorf("not all tasks were launched: %s", strings.Join(reasons, "; "))
+		}
+		return nil, nil, fmt.Errorf("no task was launched: %s", strings.Join(reasons, "; "))
 	}
-	logStreamName := logPrefix + "/" + containerName + "/" + taskArnID
-	logGroupName := *taskDefinitionOutput.TaskDefinition.ContainerDefinitions[0].LogConfiguration.Options["awslogs-group"]
-	return logGroupName, logStreamName, taskArnID
+
+	var streams []logStream
+	for _, taskArn := range taskArns {
+		taskStreams := taskLogStreams(taskDefinitionOutput.TaskDefinition, taskArn)
+		if len(taskArns) > 1 {
+			for i := range taskStreams {
+				taskStreams[i].Label = strings.TrimSuffix(shortTaskID(taskArn)+" "+taskStreams[i].Label, " ")
+			}
+		}
+		streams = append(streams, taskStreams...)
+	}
+	return taskArns, streams, nil
 }
 
-// GetLogs prints all the logs for specified LogStream sorted from earliest to latest.
-func GetLogs(sess *session.Session, logStreamName string, logGroupName string) []*cloudwatchlogs.OutputLogEvent {
-	svc := cloudwatchlogs.New(sess)
+// stopTasks stops the tasks of a run that cannot be completed.
+func stopTasks(ctx context.Context, svc *ecs.Client, cluster string, taskArns []string, reason string) {
+	for _, taskArn := range taskArns {
+		_, err := svc.StopTask(ctx, &ecs.StopTaskInput{
+			Cluster: aws.String(cluster),
+			Task:    aws.String(taskArn),
+			Reason:  aws.String(reason),
+		})
+		if err != nil {
+			printError("Got error stopping task "+taskID(taskArn)+":", err)
+		}
+	}
+}
 
-	resp, err := svc.GetLogEvents(&cloudwatchlogs.GetLogEventsInput{
-		Limit:         aws.Int64(100),
-		LogGroupName:  aws.String(logGroupName),
-		LogStreamName: aws.String(logStreamName),
-		StartFromHead: aws.Bool(true),
-	})
-	if err != nil {
-		fmt.Println("Error getting log events:")
-		fmt.Println(err.Error())
-		os.Exit(1)
+// shortTaskID returns the first 8 characters of the ID of a task, enough to
+// tell the tasks of a run apart.
+func shortTaskID(taskArn string) string {
+	id := taskID(taskArn)
+	if len(id) > 8 {
+		return id[:8]
 	}
-	return resp.Events
+	return id
 }
 
-// GetExit Returns the exit code of the function and stoppedReason
-func GetExit(sess *session.Session, ecsCluster string, task string) (int64, string) {
-	svc := ecs.New(sess)
-	output, err := svc.DescribeTasks(&ecs.DescribeTasksInput{
-		Cluster: aws.String(ecsCluster),
-		Tasks:   aws.StringSlice([]string{task}),
-	})
+// taskLogStreams returns the awslogs log streams of every container of a
+// task. Lines are labeled with the container name when there are several.
+func taskLogStreams(td *ecstypes.TaskDefinition, taskArn string) []logStream {
+	var streams []logStream
+	for _, container := range td.ContainerDefinitions {
+		logConfig := container.LogConfiguration
+		if logConfig == nil || logConfig.LogDriver != ecstypes.LogDriverAwslogs {
+			continue
+		}
+		name := aws.ToString(container.Name)
+		streams = append(streams, logStream{
+			Group:  logConfig.Options["awslogs-group"],
+			Stream: logConfig.Options["awslogs-stream-prefix"] + "/" + name + "/" + taskID(taskArn),
+			Label:  name,
+		})
+	}
+	if len(streams) == 1 {
+		streams[0].Label = ""
+	}
+	return streams
+}
+
+// networkConfiguration returns the awsvpc configuration of subnets,
+// securityGroups and --assign-public-ip, nil if none was given.
+func networkConfiguration(subnets string, securityGroups string) *ecstypes.NetworkConfiguration {
+	if subnets == "" && securityGroups == "" && !assignPublicIP {
+		return nil
+	}
+	config := &ecstypes.AwsVpcConfiguration{}
+	if subnets != "" {
+		config.Subnets = strings.Split(subnets, ",")
+	}
+	if securityGroups != "" {
+		config.SecurityGroups = strings.Split(securityGroups, ",")
+	}
+	if assignPublicIP {
+		config.AssignPublicIp = ecstypes.AssignPublicIpEnabled
+	}
+	return &ecstypes.NetworkConfiguration{AwsvpcConfiguration: config}
+}
+
+// GetLogs fetches the logs for specified LogStream from earliest to latest.
+// handle is called for every page of events as it arrives, so memory use
+// does not grow with the size of the stream. At most maxLogEvents events are
+// fetched if it is set, starting where --since and --tail say.
+func GetLogs(ctx context.Context, cfg aws.Config, logStreamName string, logGroupName string, handle func([]logstypes.OutputLogEvent)) {
+	svc := cloudwatchlogs.NewFromConfig(cfg)
+
+	fetched := 0
+	// handlePage returns false once maxLogEvents events were fetched.
+	handlePage := func(events []logstypes.OutputLogEvent) bool {
+		if maxLogEvents > 0 && fetched+len(events) > maxLogEvents {
+			events = events[:maxLogEvents-fetched]
+		}
+		handle(events)
+		fetched += len(events)
+		if maxLogEvents > 0 && fetched >= maxLogEvents {
+			fmt.Printf("Stopped after %d log events, see --max-log-events\n", fetched)
+			return false
+		}
+		return true
+	}
+	s := logStream{Group: logGroupName, Stream: logStreamName}
+	if filterMode() {
+		filter := newLogFilter(svc, s)
+		err := waitForLogStream(s, func() error { return filter.fetch(ctx, handlePage) })
+		if isNotFound(err) {
+			printError("Error filtering log events of "+logStreamName+":", err)
+		} else if err != nil {
+			exitWithError("Error filtering log events:", err)
+		}
+		return
+	}
+	input := logEventsInput(s)
+	for {
+		var resp *cloudwatchlogs.GetLogEventsOutput
+		err := waitForLogStream(s, func() (err error) {
+			resp, err = svc.GetLogEvents(ctx, input)
+			return err
+		})
+		if isNotFound(err) {
+			printError("Error getting log events of "+logStreamName+":", err)
+			return
+		}
+		if err != nil {
+			exitWithError("Error getting log events:", err)
+		}
+		if !handlePage(resp.Events) {
+			return
+		}
+		// The forward token stays the same once the end of the stream is reached.
+		if resp.NextForwardToken == nil || aws.ToString(resp.NextForwardToken) == aws.ToString(input.NextToken) {
+			return
+		}
+		nextLogEvents(input, resp.NextForwardToken)
+	}
+}
+
+// GetExit Returns the exit code of the function according to the
+// --exit-code-from policy, stoppedReason and the stopped task
+func GetExit(ctx context.Context, cfg aws.Config, ecsCluster string, task string, policy string) (int64, string, *ecstypes.Task) {
+	svc := ecs.NewFromConfig(cfg)
+	tasks, err := describeTasks(ctx, svc, ecsCluster, []string{task})
 	if err != nil {
-		fmt.Println("Got error describing task:")
-		fmt.Println(err.Error())
-		os.Exit(1)
+		exitWithError("Got error describing task:", err)
 	}
-	exitCode := *output.Tasks[0].Containers[0].ExitCode
-	stoppedReason := *output.Tasks[0].StoppedReason
-	return exitCode, stoppedReason
+	exitCode, err := taskExitCode(ctx, cfg, &tasks[0], policy)
+	if err != nil {
+		exitWithError("Got error reading exit code:", err)
+	}
+	stoppedReason := aws.ToString(tasks[0].StoppedReason)
+	return exitCode, stoppedReason, &tasks[0]
 }
 
 // Parses task
-func ParseTaskDefinition(sess *session.Session, fileName string) string {
-	svc := ecs.New(sess)
+func ParseTaskDefinition(ctx context.Context, cfg aws.Config, fileName string) string {
+	svc := ecs.NewFromConfig(cfg)
 	var ecsTaskDefinition ecs.RegisterTaskDefinitionInput
 	jsonFile, err := os.Open(fileName)
 	if err != nil {
-		fmt.Println(err)
+		exitWithError("Got error opening task definition:", err)
 	}
 	fmt.Println("Successfully Opened task definition:", fileName)
 	defer jsonFile.Close()
 	byteValue, _ := ioutil.ReadAll(jsonFile)
-	json.Unmarshal(byteValue, &ecsTaskDefinition)
-	output, err := svc.RegisterTaskDefinition(&ecsTaskDefinition)
+	if err := json.Unmarshal(byteValue, &ecsTaskDefinition); err != nil {
+		exitWithError("Got error parsing task definition:", err)
+	}
+	if platform := runtimePlatform(); platform != nil {
+		ecsTaskDefinition.RuntimePlatform = platform
+	}
+	output, err := svc.RegisterTaskDefinition(ctx, &ecsTaskDefinition)
 	if err != nil {
-		fmt.Println("Got error registering task definition:")
-		fmt.Println(err.Error())
-		os.Exit(1)
+		exitWithError("Got error registering task definition:", err)
 	}
+	justRegistered = true
 	return *output.TaskDefinition.TaskDefinitionArn
 
 }
-
-func printEvents(events []*cloudwatchlogs.OutputLogEvent) {
-	for _, event := range events {
-		// AWS returns milliseconds of unix time.
-		// So we have to transfer to second.
-		timestamp := time.Unix((*event.Timestamp / 1000), 0)
-		message := *event.Message
-		fmt.Printf("[%s] %s\n", timestamp, message)
-	}
-}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// runCmd launches a task using a preset from the config file
+var runCmd = &cobra.Command{
+	Use:   "run <preset>",
+	Short: "Run a task using a preset defined in the config file",
+	Long: `Run a task using a preset defined under "presets" in the config file.
+
+A preset maps flag names to values, for example:
+
+  presets:
+    migrate:
+      cluster: production
+      task-definition: app-migrations
+      subnets: subnet-a,subnet-b
+
+Flags given on the command line take precedence over the preset.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := applyPreset(cmd, args[0]); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		runTask(cmd)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(runCmd)
+	addRunFlags(runCmd.Flags())
+}
+
+// applyPreset sets every flag named in the preset that was not given
+// explicitly on the command line.
+func applyPreset(cmd *cobra.Command, name string) error {
+	preset := viper.GetStringMap("presets." + name)
+	if len(preset) == 0 {
+		return fmt.Errorf("preset %q is not defined in %s", name, configFileName())
+	}
+	for key, value := range preset {
+		flag := cmd.Flags().Lookup(key)
+		if flag == nil {
+			return fmt.Errorf("preset %q: unknown setting %q", name, key)
+		}
+		if flag.Changed {
+			continue
+		}
+		values, ok := value.([]interface{})
+		if !ok {
+			values = []interface{}{value}
+		}
+		for _, v := range values {
+			if err := cmd.Flags().Set(key, fmt.Sprint(v)); err != nil {
+				return fmt.Errorf("preset %q: invalid value for %s: %v", name, key, err)
+			}
+		}
+	}
+	return nil
+}
+
+func configFileName() string {
+	if f := viper.ConfigFileUsed(); f != "" {
+		return f
+	}
+	return "the config file"
+}
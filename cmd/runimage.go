@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/spf13/cobra"
+)
+
+var imageName string
+var imageCPU string
+var imageMemory string
+var imageLogGroup string
+var deregisterImageTaskDefinition bool
+
+// runImageCmd runs an image without a prepared task definition
+var runImageCmd = &cobra.Command{
+	Use:   "run-image --image repo:tag [flags] [-- command args...]",
+	Short: "Run an image in a throwaway task definition",
+	Long: `Run an image in a task definition generated on the fly, like docker run.
+
+The task definition family is named after the image and logs go to the
+CloudWatch log group given with --log-group, which is created if needed.
+Arguments after -- replace the command of the image.`,
+	Args: cobra.ArbitraryArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if imageName == "" || ecsCluster == "" {
+			cmd.Usage()
+			os.Exit(1)
+		}
+		validateRunFlags()
+		redirectOutput()
+		ctx := cmd.Context()
+		cfg := newConfig(ctx)
+		taskDefinition = registerImageTaskDefinition(ctx, cfg, args)
+		taskDefinitionFile = false
+		fmt.Println("Succesfully uploaded: ", taskDefinition)
+		if deregisterImageTaskDefinition {
+			onExit(func() {
+				_, err := ecs.NewFromConfig(cfg).DeregisterTaskDefinition(ctx, &ecs.DeregisterTaskDefinitionInput{
+					TaskDefinition: aws.String(taskDefinition),
+				})
+				if err != nil {
+					printError("Got error deregistering task definition:", err)
+				}
+			})
+		}
+		runTask(cmd)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(runImageCmd)
+	addRunFlags(runImageCmd.Flags())
+	runImageCmd.Flags().StringVarP(&imageName, "image", "", "", "Image to run")
+	runImageCmd.Flags().StringVarP(&imageCPU, "cpu", "", "256", "CPU units of the task")
+	runImageCmd.Flags().StringVarP(&imageMemory, "memory", "", "512", "Memory of the task in MiB")
+	runImageCmd.Flags().StringVarP(&imageLogGroup, "log-group", "", "/ecs-run-task", "CloudWatch log group for the task logs")
+	runImageCmd.Flags().BoolVarP(&deregisterImageTaskDefinition, "rm", "", false, "Deregister the generated task definition after the run")
+}
+
+// registerImageTaskDefinition registers a single container task definition
+// running imageName with command and returns its ARN.
+func registerImageTaskDefinition(ctx context.Context, cfg aws.Config, command []string) string {
+	name := imageContainerName(imageName)
+	container := ecstypes.ContainerDefinition{
+		Name:      aws.String(name),
+		Image:     aws.String(imageName),
+		Essential: aws.Bool(true),
+		LogConfiguration: &ecstypes.LogConfiguration{
+			LogDriver: ecstypes.LogDriverAwslogs,
+			Options: map[string]string{
+				"awslogs-group":         imageLogGroup,
+				"awslogs-region":        cfg.Region,
+				"awslogs-stream-prefix": "ecs-run-task",
+				"awslogs-create-group":  "true",
+			},
+		},
+	}
+	if len(command) > 0 {
+		container.Command = command
+	}
+	input := &ecs.RegisterTaskDefinitionInput{
+		Family:                  aws.String("ecs-run-task-" + name),
+		Cpu:                     aws.String(imageCPU),
+		Memory:                  aws.String(imageMemory),
+		NetworkMode:             ecstypes.NetworkModeAwsvpc,
+		RequiresCompatibilities: []ecstypes.Compatibility{ecstypes.Compatibility(launchType)},
+		ContainerDefinitions:    []ecstypes.ContainerDefinition{container},
+		RuntimePlatform:         runtimePlatform(),
+	}
+	if executionRoleArn != "" {
+		input.ExecutionRoleArn = aws.String(executionRoleArn)
+	}
+	output, err := ecs.NewFromConfig(cfg).RegisterTaskDefinition(ctx, input)
+	if err != nil {
+		exitWithError("Got error registering task definition:", err)
+	}
+	justRegistered = true
+	return *output.TaskDefinition.TaskDefinitionArn
+}
+
+// imageContainerName derives a container name from an image reference,
+// e.g. 123.dkr.ecr.eu-west-1.amazonaws.com/team/app:v1 becomes app.
+func imageContainerName(image string) string {
+	name := image[strings.LastIndex(image, "/")+1:]
+	if i := strings.IndexAny(name, ":@"); i >= 0 {
+		name = name[:i]
+	}
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' {
+			return r
+		}
+		return '-'
+	}, name)
+}
@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// stdinEnvName is the environment variable holding the S3 URI of the data
+// piped into ecs-run-task with --stdin-to.
+const stdinEnvName = "ECS_RUN_TASK_STDIN"
+
+var stdinTo string
+var uploads []string
+var fetchArtifactsFrom string
+var artifactsDir string
+
+// newS3Client returns an S3 client of cfg. Emulators and VPC endpoints do
+// not serve bucket subdomains, so custom endpoints are addressed by path.
+func newS3Client(cfg aws.Config) *s3.Client {
+	return s3.NewFromConfig(cfg, func(o *s3.Options) {
+		o.UsePathStyle = customEndpoint("s3") != ""
+	})
+}
+
+// uploadStdin uploads everything read from stdin to a new object below the
+// S3 prefix and returns the URI of that object.
+func uploadStdin(ctx context.Context, cfg aws.Config, prefix string) string {
+	bucket, key, err := parseS3URI(prefix)
+	if err != nil {
+		exitWithError("Got error parsing --stdin-to:", err)
+	}
+	key = joinS3Key(key, time.Now().UTC().Format("20060102T150405Z")+"-stdin")
+	fmt.Printf("Uploading stdin to s3://%s/%s...\n", bucket, key)
+	_, err = manager.NewUploader(newS3Client(cfg)).Upload(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+		Body:   os.Stdin,
+	})
+	if err != nil {
+		exitWithError("Got error uploading stdin:", err)
+	}
+	return "s3://" + bucket + "/" + key
+}
+
+// uploadFile uploads a local file given as path=s3://bucket/key and returns
+// the name of the environment variable its URI is passed to the task in,
+// ECS_RUN_TASK_UPLOAD_ followed by the upper cased file name.
+func uploadFile(ctx context.Context, cfg aws.Config, spec string) (string, string) {
+	parts := strings.SplitN(spec, "=", 2)
+	if len(parts) != 2 {
+		fmt.Printf("Invalid --upload %q, expected path=s3://bucket/key\n", spec)
+		exit(1)
+	}
+	path, uri := parts[0], parts[1]
+	bucket, key, err := parseS3URI(uri)
+	if err != nil {
+		exitWithError("Got error parsing --upload:", err)
+	}
+	if key == "" || strings.HasSuffix(key, "/") {
+		key = joinS3Key(key, filepath.Base(path))
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		exitWithError("Got error opening upload:", err)
+	}
+	defer f.Close()
+	fmt.Printf("Uploading %s to s3://%s/%s...\n", path, bucket, key)
+	_, err = manager.NewUploader(newS3Client(cfg)).Upload(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+		Body:   f,
+	})
+	if err != nil {
+		exitWithError("Got error uploading file:", err)
+	}
+	return uploadEnvName(path), "s3://" + bucket + "/" + key
+}
+
+// validateUploadFlags exits if two --upload files would pass their URI in
+// the same environment variable, e.g. a/config.json and b/config.json.
+func validateUploadFlags() {
+	paths := map[string]string{}
+	for _, spec := range uploads {
+		path := strings.SplitN(spec, "=", 2)[0]
+		name := uploadEnvName(path)
+		if other, ok := paths[name]; ok {
+			fmt.Printf("--upload %s and %s would both be passed in %s, rename one of them\n", other, path, name)
+			exit(1)
+		}
+		paths[name] = path
+	}
+}
+
+// uploadEnvName turns the file name of path into an environment variable
+// name, e.g. config/app.yaml becomes ECS_RUN_TASK_UPLOAD_APP_YAML.
+func uploadEnvName(path string) string {
+	name := strings.Map(func(r rune) rune {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, strings.ToUpper(filepath.Base(path)))
+	return "ECS_RUN_TASK_UPLOAD_" + name
+}
+
+// fetchArtifacts downloads every object below the S3 prefix uri to dir,
+// keeping their paths relative to the prefix. {taskId} in uri is replaced
+// with taskID.
+func fetchArtifacts(ctx context.Context, cfg aws.Config, uri string, taskID string, dir string) error {
+	bucket, prefix, err := parseS3URI(strings.Replace(uri, "{taskId}", taskID, -1))
+	if err != nil {
+		return err
+	}
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	fmt.Printf("Downloading artifacts from s3://%s/%s to %s...\n", bucket, prefix, dir)
+	svc := newS3Client(cfg)
+	downloader := manager.NewDownloader(svc)
+	var keys []string
+	paginator := s3.NewListObjectsV2Paginator(svc, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, object := range page.Contents {
+			keys = append(keys, *object.Key)
+		}
+	}
+	downloaded := 0
+	for _, key := range keys {
+		rel := strings.TrimPrefix(key, prefix)
+		if rel == "" || strings.HasSuffix(rel, "/") {
+			continue
+		}
+		path := filepath.Join(dir, filepath.FromSlash(rel))
+		inDir, err := filepath.Rel(dir, path)
+		if err != nil || inDir == "." || inDir == ".." || strings.HasPrefix(inDir, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("refusing to write %s outside of %s", key, dir)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		_, err = downloader.Download(ctx, f, &s3.GetObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(key),
+		})
+		f.Close()
+		if err != nil {
+			return err
+		}
+		fmt.Println("Downloaded", path)
+		downloaded++
+	}
+	fmt.Printf("Downloaded %d artifacts\n", downloaded)
+	return nil
+}
+
+// parseS3URI splits an s3://bucket/key URI into bucket and key.
+func parseS3URI(uri string) (string, string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", "", err
+	}
+	if u.Scheme != "s3" || u.Host == "" {
+		return "", "", fmt.Errorf("%q is not an s3://bucket/key URI", uri)
+	}
+	return u.Host, strings.TrimPrefix(u.Path, "/"), nil
+}
+
+func joinS3Key(prefix string, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return strings.TrimSuffix(prefix, "/") + "/" + name
+}
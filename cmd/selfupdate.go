@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const releasesURL = "https://api.github.com/repos/laur1s/ecs-run-task/releases/latest"
+
+var selfUpdateCheck bool
+var selfUpdateForce bool
+
+type githubRelease struct {
+	TagName string `json:"tag_name"`
+	Assets  []struct {
+		Name               string `json:"name"`
+		BrowserDownloadURL string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+// selfUpdateCmd replaces the running binary with the latest GitHub release
+var selfUpdateCmd = &cobra.Command{
+	Use:   "self-update",
+	Short: "Update ecs-run-task to the latest release",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		release, err := latestRelease()
+		if err != nil {
+			exitWithError("Got error checking for releases:", err)
+		}
+		if release.TagName == version && !selfUpdateForce {
+			fmt.Println("Already up to date:", version)
+			return
+		}
+		fmt.Printf("Current version %s, latest release %s\n", version, release.TagName)
+		if selfUpdateCheck {
+			return
+		}
+		if err := installRelease(release); err != nil {
+			exitWithError("Got error updating ecs-run-task:", err)
+		}
+		fmt.Println("Successfully updated to", release.TagName)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(selfUpdateCmd)
+	selfUpdateCmd.Flags().BoolVarP(&selfUpdateCheck, "check", "", false, "Only check whether a newer release is available")
+	selfUpdateCmd.Flags().BoolVarP(&selfUpdateForce, "force", "", false, "Reinstall even if already on the latest release")
+}
+
+// installRelease downloads the binary for this platform from release, verifies it
+// against the release checksums and replaces the running executable with it.
+func installRelease(release *githubRelease) error {
+	assetName := fmt.Sprintf("ecs-run-task_%s_%s", runtime.GOOS, runtime.GOARCH)
+	if runtime.GOOS == "windows" {
+		assetName += ".exe"
+	}
+	var binaryURL, checksumsURL string
+	for _, asset := range release.Assets {
+		switch asset.Name {
+		case assetName:
+			binaryURL = asset.BrowserDownloadURL
+		case "checksums.txt":
+			checksumsURL = asset.BrowserDownloadURL
+		}
+	}
+	if binaryURL == "" {
+		return fmt.Errorf("release %s has no asset named %s", release.TagName, assetName)
+	}
+	if checksumsURL == "" {
+		return fmt.Errorf("release %s has no checksums.txt, refusing to install an unverified binary", release.TagName)
+	}
+
+	checksums, err := download(checksumsURL)
+	if err != nil {
+		return err
+	}
+	expected, err := findChecksum(checksums, assetName)
+	if err != nil {
+		return err
+	}
+	binary, err := download(binaryURL)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(binary)
+	if actual := hex.EncodeToString(sum[:]); actual != expected {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", assetName, expected, actual)
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	executable, err = filepath.EvalSymlinks(executable)
+	if err != nil {
+		return err
+	}
+	// Write next to the executable so the final rename stays on one filesystem.
+	tmp, err := ioutil.TempFile(filepath.Dir(executable), ".ecs-run-task-update-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(binary); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return err
+	}
+	// Windows refuses to overwrite a running executable but allows renaming it.
+	old := executable + ".old"
+	os.Remove(old)
+	if err := os.Rename(executable, old); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), executable); err != nil {
+		os.Rename(old, executable)
+		return err
+	}
+	os.Remove(old)
+	return nil
+}
+
+func latestRelease() (*githubRelease, error) {
+	body, err := download(releasesURL)
+	if err != nil {
+		return nil, err
+	}
+	var release githubRelease
+	if err := json.Unmarshal(body, &release); err != nil {
+		return nil, err
+	}
+	return &release, nil
+}
+
+func download(url string) ([]byte, error) {
+	resp, err := newHTTPClient(0).Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// findChecksum looks up name in a sha256sum formatted checksums file.
+func findChecksum(checksums []byte, name string) (string, error) {
+	scanner := bufio.NewScanner(strings.NewReader(string(checksums)))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return fields[0], nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("no checksum for %s in checksums.txt", name)
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+var asciiSpinnerFrames = []string{"|", "/", "-", "\\"}
+
+// statusLineTerminal is the terminal stdout was while a status line is
+// shown, and nil otherwise.
+var statusLineTerminal *os.File
+
+// stopShownStatusLine stops the status line shown, nil if there is none.
+var stopShownStatusLine func()
+var stopShownStatusLineMu sync.Mutex
+
+var stopStatusLineOnExit sync.Once
+
+// stopStatusLine stops the status line shown, if any, so that what is
+// printed next goes straight to the terminal.
+func stopStatusLine() {
+	stopShownStatusLineMu.Lock()
+	stop := stopShownStatusLine
+	stopShownStatusLineMu.Unlock()
+	if stop != nil {
+		stop()
+	}
+}
+
+// startWaitDisplay shows how waiting for the tasks of state goes: a status
+// line redrawn in place on terminals, or else heartbeat lines. Logs that are
+// followed are printed as they come instead of a status line. The returned
+// function stops the display.
+func startWaitDisplay(state *runState) func() {
+	if state.Follow || !isTerminal(os.Stdout) {
+		return startHeartbeat(state)
+	}
+	stop, err := startStatusLine(func() string {
+		return fmt.Sprintf("Waiting for task: %s (%s)", taskStatusSummary(state.tasks()), time.Since(state.StartedAt).Round(time.Second))
+	})
+	if err != nil {
+		return startHeartbeat(state)
+	}
+	return stop
+}
+
+// startStatusLine shows a spinner and the text returned by status on the
+// last line of the terminal, redrawn in place. Everything printed meanwhile
+// is passed through above it: os.Stdout is replaced by a pipe until the
+// returned function is called.
+func startStatusLine(status func() string) (func(), error) {
+	terminal := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	frames := spinnerFrames
+	if noEmoji || asciiOutput {
+		frames = asciiSpinnerFrames
+	}
+	var mu sync.Mutex
+	drawn, atLineStart := false, true
+	clear := func() {
+		if drawn {
+			terminal.WriteString("\r\x1b[K")
+			drawn = false
+		}
+	}
+	copied := make(chan struct{})
+	go func() {
+		defer close(copied)
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := r.Read(buf)
+			if n > 0 {
+				mu.Lock()
+				clear()
+				terminal.Write(buf[:n])
+				atLineStart = buf[n-1] == '\n'
+				mu.Unlock()
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		for frame := 0; ; frame++ {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			mu.Lock()
+			// Do not draw over a prompt waiting for an answer.
+			if atLineStart {
+				line := frames[frame%len(frames)] + " " + strings.Replace(status(), "\n", " ", -1)
+				terminal.WriteString("\r\x1b[K" + line)
+				drawn = true
+			}
+			mu.Unlock()
+		}
+	}()
+	statusLineTerminal = terminal
+	os.Stdout = w
+	var once sync.Once
+	stopLine := func() {
+		once.Do(func() {
+			close(stop)
+			<-stopped
+			os.Stdout = terminal
+			statusLineTerminal = nil
+			w.Close()
+			<-copied
+			mu.Lock()
+			clear()
+			mu.Unlock()
+		})
+	}
+	stopShownStatusLineMu.Lock()
+	stopShownStatusLine = stopLine
+	stopShownStatusLineMu.Unlock()
+	// Output printed right before exiting would be lost in the pipe.
+	stopStatusLineOnExit.Do(func() {
+		onExit(stopStatusLine)
+	})
+	return stopLine, nil
+}
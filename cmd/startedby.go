@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"strings"
+)
+
+var startedByValue string
+var taskGroup string
+var referenceID string
+
+// startedBy returns the startedBy value attached to tasks launched by this
+// tool: --started-by, or else ecs-run-task/<user> so tasks can be traced back
+// to whoever launched them. ECS limits it to 36 letters, numbers, hyphens,
+// slashes and underscores, other characters of the user name are replaced.
+func startedBy() string {
+	if startedByValue != "" {
+		return startedByValue
+	}
+	s := startedByPrefix + "/" + strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, currentUser())
+	if len(s) > 36 {
+		s = s[:36]
+	}
+	return s
+}
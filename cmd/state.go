@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// runState is everything needed to pick up monitoring a launched task. It
+// is saved as soon as the task was launched, so a run can be resumed after
+// the CLI or the machine running it crashed.
+type runState struct {
+	TaskArn           string        `json:"taskArn"`
+	TaskArns          []string      `json:"taskArns,omitempty"`
+	Owner             string        `json:"owner"`
+	Region            string        `json:"region,omitempty"`
+	Cluster           string        `json:"cluster"`
+	TaskDefinition    string        `json:"taskDefinition"`
+	LogStreams        []logStream   `json:"logStreams"`
+	StartedAt         time.Time     `json:"startedAt"`
+	MaxRunTime        time.Duration `json:"maxRunTime,omitempty"`
+	WaitTimeout       time.Duration `json:"waitTimeout"`
+	UsageSummary      bool          `json:"usageSummary,omitempty"`
+	Follow            bool          `json:"follow,omitempty"`
+	FetchArtifacts    string        `json:"fetchArtifacts,omitempty"`
+	ArtifactsDir      string        `json:"artifactsDir,omitempty"`
+	ResultAt          string        `json:"resultAt,omitempty"`
+	AuditLog          string        `json:"auditLog,omitempty"`
+	ArchiveLogs       string        `json:"archiveLogs,omitempty"`
+	LogQuery          string        `json:"logQuery,omitempty"`
+	ExitCodeFrom      string        `json:"exitCodeFrom,omitempty"`
+	Timeline          bool          `json:"timeline,omitempty"`
+	FailOnLogPattern  string        `json:"failOnLogPattern,omitempty"`
+	StopOnLogPattern  bool          `json:"stopOnLogPattern,omitempty"`
+	DurationHistory   string        `json:"durationHistory,omitempty"`
+	SlowdownThreshold int           `json:"slowdownThreshold,omitempty"`
+	FailOnSlowdown    bool          `json:"failOnSlowdown,omitempty"`
+	History           *historyEntry `json:"history,omitempty"`
+	LockTable         string        `json:"lockTable,omitempty"`
+	LockKey           string        `json:"lockKey,omitempty"`
+	LockOwner         string        `json:"lockOwner,omitempty"`
+	LockRegion        string        `json:"lockRegion,omitempty"`
+}
+
+// tasks returns the ARNs of all tasks of the run. Runs of a single task
+// only record TaskArn.
+func (s *runState) tasks() []string {
+	if len(s.TaskArns) > 0 {
+		return s.TaskArns
+	}
+	return []string{s.TaskArn}
+}
+
+// stateDir returns the directory state files are kept in,
+// ~/.ecs-run-task/state.
+func stateDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".ecs-run-task", "state")
+}
+
+func stateFile(taskArn string) string {
+	return filepath.Join(stateDir(), taskID(taskArn)+".json")
+}
+
+// saveState writes the state of a launched task to its state file.
+func saveState(state *runState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(stateDir(), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(stateFile(state.TaskArn), data, 0600)
+}
+
+// loadState reads the state file of the task with the given ID.
+func loadState(id string) (*runState, error) {
+	data, err := ioutil.ReadFile(stateFile(id))
+	if err != nil {
+		return nil, err
+	}
+	var state runState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// listStates returns the IDs of all tasks with a state file.
+func listStates() ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(stateDir(), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, f := range files {
+		ids = append(ids, filepath.Base(f[:len(f)-len(".json")]))
+	}
+	return ids, nil
+}
+
+// lockConfig returns the configuration to release the lock of the run
+// with, cfg if the lock is in the region of the task. The lock table stays
+// in the region the run started in when the task failed over to another.
+func (s *runState) lockConfig(ctx context.Context, cfg aws.Config) aws.Config {
+	if s.LockRegion == "" || s.LockRegion == cfg.Region {
+		return cfg
+	}
+	return newRegionConfig(ctx, s.LockRegion)
+}
+
+// removeState deletes the state file of a finished run.
+func removeState(state *runState) {
+	if state.TaskArn != "" {
+		os.Remove(stateFile(state.TaskArn))
+	}
+}
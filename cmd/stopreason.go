@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+// stopCategory classifies why a task stopped, so automation can branch on
+// the kind of failure without parsing stop reasons.
+type stopCategory string
+
+const (
+	stopCategoryCompleted         stopCategory = "Completed"
+	stopCategoryEssentialExited   stopCategory = "EssentialExited"
+	stopCategoryOOMKilled         stopCategory = "OOMKilled"
+	stopCategoryPullFailure       stopCategory = "PullFailure"
+	stopCategorySpotInterruption  stopCategory = "SpotInterruption"
+	stopCategoryUserStopped       stopCategory = "UserStopped"
+	stopCategoryTimeout           stopCategory = "Timeout"
+	stopCategoryTaskFailedToStart stopCategory = "TaskFailedToStart"
+	stopCategoryUnknown           stopCategory = "Unknown"
+)
+
+// classifyTask returns the stop category of a stopped task.
+func classifyTask(task *ecstypes.Task) stopCategory {
+	reasons := []string{aws.ToString(task.StoppedReason)}
+	for _, container := range task.Containers {
+		reasons = append(reasons, aws.ToString(container.Reason))
+	}
+	return classifyStop(task.StopCode, reasons...)
+}
+
+// classifyStop returns the stop category for an ECS stop code and the stop
+// reasons of a task and its containers.
+func classifyStop(stopCode ecstypes.TaskStopCode, reasons ...string) stopCategory {
+	reason := strings.Join(reasons, "\n")
+	switch {
+	case strings.Contains(reason, timeoutStopReason):
+		return stopCategoryTimeout
+	case strings.Contains(reason, "OutOfMemoryError") || strings.Contains(reason, "OOMKilled"):
+		return stopCategoryOOMKilled
+	case strings.Contains(reason, "CannotPullContainerError") || strings.Contains(reason, "pull image"):
+		return stopCategoryPullFailure
+	case stopCode == ecstypes.TaskStopCodeSpotInterruption || stopCode == ecstypes.TaskStopCodeTerminationNotice:
+		return stopCategorySpotInterruption
+	case stopCode == ecstypes.TaskStopCodeUserInitiated:
+		return stopCategoryUserStopped
+	case stopCode == ecstypes.TaskStopCodeTaskFailedToStart:
+		return stopCategoryTaskFailedToStart
+	case stopCode == ecstypes.TaskStopCodeEssentialContainerExited:
+		return stopCategoryEssentialExited
+	case stopCode == "" && strings.TrimSpace(reason) == "":
+		return stopCategoryCompleted
+	}
+	return stopCategoryUnknown
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"testing"
+
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+func TestClassifyStop(t *testing.T) {
+	tests := []struct {
+		stopCode ecstypes.TaskStopCode
+		reasons  []string
+		want     stopCategory
+	}{
+		{"", nil, stopCategoryCompleted},
+		{"", []string{"", ""}, stopCategoryCompleted},
+		{ecstypes.TaskStopCodeEssentialContainerExited, []string{"Essential container in task exited"}, stopCategoryEssentialExited},
+		{ecstypes.TaskStopCodeEssentialContainerExited, []string{"", "OutOfMemoryError: Container killed due to memory usage"}, stopCategoryOOMKilled},
+		{ecstypes.TaskStopCodeTaskFailedToStart, []string{"CannotPullContainerError: pull image manifest has been retried 5 times"}, stopCategoryPullFailure},
+		{ecstypes.TaskStopCodeTaskFailedToStart, []string{"ResourceInitializationError: unable to pull secrets"}, stopCategoryTaskFailedToStart},
+		{ecstypes.TaskStopCodeSpotInterruption, []string{"Your Spot Task was interrupted."}, stopCategorySpotInterruption},
+		{ecstypes.TaskStopCodeTerminationNotice, nil, stopCategorySpotInterruption},
+		{ecstypes.TaskStopCodeUserInitiated, []string{interruptStopReason}, stopCategoryUserStopped},
+		{ecstypes.TaskStopCodeUserInitiated, []string{timeoutStopReason + " of 1h0m0s"}, stopCategoryTimeout},
+		{"", []string{"Host EC2 (instance i-0123) terminated."}, stopCategoryUnknown},
+	}
+	for _, test := range tests {
+		if got := classifyStop(test.stopCode, test.reasons...); got != test.want {
+			t.Errorf("classifyStop(%q, %q) = %s, want %s", test.stopCode, test.reasons, got, test.want)
+		}
+	}
+}
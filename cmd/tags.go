@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+var taskTags []string
+var propagateTags string
+
+// validateTagFlags exits if --tag or --propagate-tags have an invalid value.
+func validateTagFlags() {
+	for _, tag := range taskTags {
+		splitKeyValue("tag", tag)
+	}
+	values := enumValues(ecstypes.PropagateTags("").Values())
+	if propagateTags != "" && !contains(values, propagateTags) {
+		fmt.Printf("Invalid --propagate-tags %q, expected one of %v\n", propagateTags, values)
+		exit(1)
+	}
+}
+
+// resourceTags returns the tags given with --tag, nil if there are none.
+func resourceTags() []ecstypes.Tag {
+	var tags []ecstypes.Tag
+	for _, tag := range taskTags {
+		key, value := splitKeyValue("tag", tag)
+		tags = append(tags, ecstypes.Tag{Key: aws.String(key), Value: aws.String(value)})
+	}
+	return tags
+}
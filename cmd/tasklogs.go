@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/spf13/cobra"
+)
+
+// logsCmd prints the logs of a task launched earlier
+var logsCmd = &cobra.Command{
+	Use:   "logs <task-arn>",
+	Short: "Print the logs of a task",
+	Long: `Print the CloudWatch logs of every container of a task, running or
+finished. The log streams are found through the task definition of the task.
+
+ECS forgets stopped tasks after about an hour. For older tasks pass the ID of
+the task together with --cluster and --task-definition.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		validateLogFlags()
+		openLogFile()
+		ctx := cmd.Context()
+		cfg, cluster, _, task, err := findTask(ctx, args[0])
+		if err != nil && (taskDefinition == "" || cluster == "") {
+			exitWithError("Got error describing task:", err)
+		}
+		taskArn, definition := args[0], taskDefinition
+		if task != nil {
+			taskArn, definition = aws.ToString(task.TaskArn), aws.ToString(task.TaskDefinitionArn)
+		}
+		streams := taskLogStreams(describeTaskDefinition(ctx, cfg, definition), taskArn)
+		if len(streams) == 0 {
+			fmt.Println("No container of the task logs to CloudWatch Logs")
+			return
+		}
+		if !followLogs || task == nil || aws.ToString(task.LastStatus) == string(ecstypes.DesiredStatusStopped) {
+			printLogStreams(ctx, cfg, streams)
+			return
+		}
+		stop := make(chan struct{})
+		go func() {
+			_, err := waitForTask(ctx, ecs.NewFromConfig(cfg), cluster, taskArn, ecstypes.DesiredStatusStopped)
+			if err != nil {
+				printError("Got error waiting for the task:", err)
+			}
+			close(stop)
+		}()
+		followStreams(ctx, cfg, streams, stop)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(logsCmd)
+	flags := logsCmd.Flags()
+	flags.StringVarP(&ecsCluster, "cluster", "c", "", "Name of the Cluster, if the task is given by ID")
+	flags.StringVarP(&taskDefinition, "task-definition", "t", "", "Task definition of the task, if ECS no longer knows the task")
+	flags.BoolVarP(&followLogs, "follow", "", false, "Keep printing new logs until the task stopped")
+	flags.BoolVarP(&useLiveTail, "live-tail", "", false, "With --follow, have CloudWatch Logs Live Tail push new log lines instead of polling for them")
+	flags.IntVarP(&maxLogEvents, "max-log-events", "", 0, "Print at most this many log events per stream, 0 for all")
+	flags.StringVarP(&logTime, "log-time", "", logTimeEvent, "Time to print and order log lines by: event (set by the container) or ingestion (set by CloudWatch)")
+	addTimestampsFlag(flags)
+	flags.DurationVarP(&logStreamWait, "log-stream-wait", "", 30*time.Second, "How long to wait for a log stream that does not exist yet before giving up on its logs")
+	flags.DurationVarP(&logsSince, "since", "", 0, "Only print log events of this long ago or later, e.g. 10m")
+	flags.IntVarP(&logTail, "tail", "", 0, "Only print the last this many log events of each stream, 0 for all")
+	flags.StringVarP(&logGrep, "grep", "", "", "Only print log lines matching this regular expression")
+	flags.StringVarP(&logGrepV, "grep-v", "", "", "Do not print log lines matching this regular expression")
+	flags.StringVarP(&logFile, "log-file", "", "", "Also write the printed log lines to this file")
+	flags.BoolVarP(&logFilePrefixes, "log-file-prefixes", "", false, "Write log lines to --log-file with their timestamp and container prefix")
+	flags.StringArrayVarP(&maskFlags, "mask", "", nil, "Replace this value, or regular expression given as /regex/, with *** in printed logs (repeatable)")
+	flags.StringVarP(&logFilterPattern, "filter-pattern", "", "", "Only fetch log events matching this CloudWatch Logs filter pattern")
+	flags.BoolVarP(&filterLogs, "filter-logs", "", false, "Fetch log events with FilterLogEvents from every log stream whose name starts with the name of a container's stream")
+}
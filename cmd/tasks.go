@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+// describeTasksBatchSize is the most tasks DescribeTasks accepts per call.
+const describeTasksBatchSize = 100
+
+// describeTasks describes any number of tasks, splitting the ARNs into
+// batches DescribeTasks accepts. Tasks ECS reports as failures are returned
+// as an error.
+func describeTasks(ctx context.Context, svc *ecs.Client, cluster string, taskArns []string) ([]ecstypes.Task, error) {
+	var tasks []ecstypes.Task
+	for start := 0; start < len(taskArns); start += describeTasksBatchSize {
+		end := start + describeTasksBatchSize
+		if end > len(taskArns) {
+			end = len(taskArns)
+		}
+		output, err := svc.DescribeTasks(ctx, &ecs.DescribeTasksInput{
+			Cluster: aws.String(cluster),
+			Tasks:   taskArns[start:end],
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(output.Failures) > 0 {
+			failure := output.Failures[0]
+			return nil, fmt.Errorf("describing task %s: %s", aws.ToString(failure.Arn), aws.ToString(failure.Reason))
+		}
+		tasks = append(tasks, output.Tasks...)
+	}
+	return tasks, nil
+}
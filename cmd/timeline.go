@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+// showTimeline prints the state transitions of tasks while waiting and
+// their timeline once they stopped.
+var showTimeline bool
+
+// taskTimes returns the lifecycle timestamps ECS recorded for task, in
+// order.
+func taskTimes(task *ecstypes.Task) []taskTime {
+	var times []taskTime
+	for _, t := range []struct {
+		name string
+		time *time.Time
+	}{
+		{"created", task.CreatedAt},
+		{"pull started", task.PullStartedAt},
+		{"pull stopped", task.PullStoppedAt},
+		{"started", task.StartedAt},
+		{"stopping", task.StoppingAt},
+		{"execution stopped", task.ExecutionStoppedAt},
+		{"stopped", task.StoppedAt},
+	} {
+		if t.time != nil {
+			times = append(times, taskTime{t.name, *t.time})
+		}
+	}
+	return times
+}
+
+// printTransition prints that a task went from one status to another after
+// spending since in the first.
+func printTransition(taskArn string, from string, to string, since time.Duration) {
+	if from == "" {
+		fmt.Printf("[%s] Task %s is %s\n", time.Now().Format("15:04:05"), taskID(taskArn), to)
+		return
+	}
+	fmt.Printf("[%s] Task %s is %s after %s %s\n", time.Now().Format("15:04:05"), taskID(taskArn), to, since.Round(time.Second), from)
+}
+
+// printTaskTimeline prints when a stopped task reached each stage of its
+// lifecycle and how long each stage took, e.g. pulling its images.
+func printTaskTimeline(task *ecstypes.Task) {
+	fmt.Printf("Timeline of task %s:\n", taskID(aws.ToString(task.TaskArn)))
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "EVENT\tTIME\tSINCE PREVIOUS")
+	var previous time.Time
+	for _, t := range taskTimes(task) {
+		since := ""
+		if !previous.IsZero() {
+			since = "+" + t.Time.Sub(previous).Round(time.Second).String()
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\n", t.Name, t.Time.Local().Format("15:04:05"), since)
+		previous = t.Time
+	}
+	table.Flush()
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+)
+
+var noEmoji bool
+var asciiOutput bool
+var noColor bool
+
+const (
+	colorRed    = "31"
+	colorGreen  = "32"
+	colorYellow = "33"
+	colorBlue   = "34"
+)
+
+// labelColors are the colors log prefixes are picked from.
+var labelColors = []string{"36", "35", "32", "33", "34", "96", "95", "92", "93", "94"}
+
+// colorEnabled reports whether output may contain ANSI colors. Colors are
+// only used on terminals and never when NO_COLOR is set (https://no-color.org)
+// or --no-color is passed.
+func colorEnabled() bool {
+	if noColor || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	return statusLineTerminal != nil || isTerminal(os.Stdout)
+}
+
+func colorize(color string, s string) string {
+	if !colorEnabled() {
+		return s
+	}
+	return "\x1b[" + color + "m" + s + "\x1b[0m"
+}
+
+// labelColor returns the color of a log prefix. The same label always gets
+// the same color, so interleaved lines of several sources can be told apart.
+func labelColor(label string) string {
+	h := fnv.New32a()
+	h.Write([]byte(label))
+	return labelColors[h.Sum32()%uint32(len(labelColors))]
+}
+
+// symbol returns fancy unless emoji or non-ASCII output was disabled, in
+// which case plain is used. Callers must not rely on the symbol alone to
+// convey meaning.
+func symbol(fancy string, plain string) string {
+	if noEmoji || asciiOutput {
+		return plain
+	}
+	return fancy
+}
+
+// printStatus prints the final outcome of a run. The outcome is always
+// spelled out so it does not depend on color or symbols.
+func printStatus(succeeded bool, detail string) {
+	if succeeded {
+		fmt.Println(symbol("✅ ", "") + colorize(colorGreen, "SUCCEEDED") + ": " + detail)
+	} else {
+		fmt.Println(symbol("❌ ", "") + colorize(colorRed, "FAILED") + ": " + detail)
+	}
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/spf13/cobra"
+)
+
+// Build metadata, set at build time with e.g.
+// -ldflags "-X github.com/laur1s/ecs-run-task/cmd.version=v1.2.3 -X github.com/laur1s/ecs-run-task/cmd.commit=abc123"
+var version = "dev"
+var commit = "none"
+var date = "unknown"
+
+var versionOutput string
+
+// versionCmd prints the build metadata of this binary
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Print the version of ecs-run-task",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		switch versionOutput {
+		case "json":
+			out, _ := json.MarshalIndent(map[string]string{
+				"version":   version,
+				"commit":    commit,
+				"date":      date,
+				"goVersion": runtime.Version(),
+				"platform":  runtime.GOOS + "/" + runtime.GOARCH,
+			}, "", "  ")
+			fmt.Println(string(out))
+		case "text":
+			fmt.Printf("ecs-run-task %s (commit %s, built %s, %s %s/%s)\n", version, commit, date, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+		default:
+			fmt.Println("Unknown output format:", versionOutput)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(versionCmd)
+	versionCmd.Flags().StringVarP(&versionOutput, "output", "o", "text", "Output format: text or json")
+}
@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+// defaultWaitTimeout bounds how long we wait for a task by default, the same
+// limit as the SDK waiter used before (100 attempts 6 seconds apart).
+const defaultWaitTimeout = 10 * time.Minute
+
+// waitTimeout is how long to wait for a task to stop, 0 for no limit.
+var waitTimeout = defaultWaitTimeout
+
+// waitTimeoutValue is a duration flag that also accepts "unlimited".
+type waitTimeoutValue struct{ d *time.Duration }
+
+func (v waitTimeoutValue) String() string {
+	if *v.d == 0 {
+		return "unlimited"
+	}
+	return v.d.String()
+}
+
+func (v waitTimeoutValue) Set(s string) error {
+	if s == "unlimited" {
+		*v.d = 0
+		return nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	if d < 0 {
+		return fmt.Errorf("negative duration")
+	}
+	*v.d = d
+	return nil
+}
+
+func (v waitTimeoutValue) Type() string {
+	return "duration"
+}
+
+var pollInterval time.Duration
+var maxPollInterval time.Duration
+var verbose bool
+
+// onTaskRunning, if set, is called when a wait sees a task running.
+var onTaskRunning func(task *ecstypes.Task)
+
+// taskStatusOrder ranks the lifecycle states of a task so waits can tell
+// whether a desired state was reached or passed.
+var taskStatusOrder = map[string]int{
+	"PROVISIONING":   0,
+	"PENDING":        1,
+	"ACTIVATING":     2,
+	"RUNNING":        3,
+	"DEACTIVATING":   4,
+	"STOPPING":       5,
+	"DEPROVISIONING": 6,
+	"STOPPED":        7,
+}
+
+// waitForTask polls the task until its last status reached or passed
+// desiredStatus, backing off exponentially with jitter between polls. It
+// returns the last description of the task.
+func waitForTask(ctx context.Context, svc *ecs.Client, cluster string, taskArn string, desiredStatus ecstypes.DesiredStatus) (*ecstypes.Task, error) {
+	var lastStatus string
+	var lastChange time.Time
+	for attempt := 0; ; attempt++ {
+		tasks, err := describeTasks(ctx, svc, cluster, []string{taskArn})
+		if err != nil {
+			return nil, err
+		}
+		task := &tasks[0]
+		status := aws.ToString(task.LastStatus)
+		taskStatuses.Store(taskArn, status)
+		if status != lastStatus {
+			emitProgress(progressEvent{Event: progressStatusChanged, TaskArn: taskArn, Status: status})
+			if showTimeline {
+				printTransition(taskArn, lastStatus, status, time.Since(lastChange))
+			}
+			lastStatus, lastChange = status, time.Now()
+			if status == string(ecstypes.DesiredStatusRunning) && onTaskRunning != nil {
+				onTaskRunning(task)
+			}
+		}
+		if taskStatusOrder[status] >= taskStatusOrder[string(desiredStatus)] {
+			return task, nil
+		}
+		delay := pollDelay(attempt)
+		if verbose {
+			fmt.Printf("Check %d: task is %s, next check in %s\n", attempt+1, status, delay.Round(time.Second))
+		}
+		select {
+		case <-ctx.Done():
+			return task, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// pollDelay returns how long to sleep before poll attempt+1: pollInterval
+// doubled per attempt up to maxPollInterval, with the upper half jittered so
+// many parallel runs do not poll in lockstep.
+func pollDelay(attempt int) time.Duration {
+	delay := pollInterval
+	if delay <= 0 {
+		delay = time.Second
+	}
+	for i := 0; i < attempt && delay < maxPollInterval; i++ {
+		delay *= 2
+	}
+	if delay > maxPollInterval && maxPollInterval > 0 {
+		delay = maxPollInterval
+	}
+	half := delay / 2
+	if half <= 0 {
+		return delay
+	}
+	return half + time.Duration(rand.Int63n(int64(half)))
+}
+
+// waitForStop waits until the task stopped. Once the task runs longer than
+// maxRunTime since startedAt it is stopped with timeoutStopReason. Without a
+// maxRunTime waiting gives up after waitTimeout.
+func waitForStop(ctx context.Context, svc *ecs.Client, cluster string, taskArn string, startedAt time.Time) error {
+	waitCtx := ctx
+	if waitTimeout > 0 && maxRunTime == 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, waitTimeout)
+		defer cancel()
+	}
+	runCtx := waitCtx
+	if maxRunTime > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(waitCtx, maxRunTime-time.Since(startedAt))
+		defer cancel()
+	}
+	_, err := waitForTask(runCtx, svc, cluster, taskArn, ecstypes.DesiredStatusStopped)
+	if runCtx.Err() == nil {
+		return err
+	}
+	if waitCtx.Err() != nil {
+		return fmt.Errorf("task %s did not stop within --wait-timeout %s, it is still running", taskArn, waitTimeout)
+	}
+
+	fmt.Printf("Task exceeded the maximum run time of %s, stopping it...\n", maxRunTime)
+	_, err = svc.StopTask(ctx, &ecs.StopTaskInput{
+		Cluster: aws.String(cluster),
+		Task:    aws.String(taskArn),
+		Reason:  aws.String(fmt.Sprintf("%s of %s", timeoutStopReason, maxRunTime)),
+	})
+	if err != nil {
+		return err
+	}
+	stopCtx, cancel := context.WithTimeout(ctx, defaultWaitTimeout)
+	defer cancel()
+	_, err = waitForTask(stopCtx, svc, cluster, taskArn, ecstypes.DesiredStatusStopped)
+	return err
+}
+
+// waitForStops waits until all tasks stopped, see waitForStop. It returns
+// the first error waiting for any of them.
+func waitForStops(ctx context.Context, svc *ecs.Client, cluster string, taskArns []string, startedAt time.Time) error {
+	errs := make([]error, len(taskArns))
+	var wg sync.WaitGroup
+	for i, taskArn := range taskArns {
+		wg.Add(1)
+		go func(i int, taskArn string) {
+			defer wg.Done()
+			errs[i] = waitForStop(ctx, svc, cluster, taskArn, startedAt)
+		}(i, taskArn)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPollDelay(t *testing.T) {
+	defer func(interval, max time.Duration) {
+		pollInterval, maxPollInterval = interval, max
+	}(pollInterval, maxPollInterval)
+	tests := []struct {
+		interval time.Duration
+		max      time.Duration
+		attempt  int
+		want     time.Duration
+	}{
+		{2 * time.Second, 30 * time.Second, 0, 2 * time.Second},
+		{2 * time.Second, 30 * time.Second, 1, 4 * time.Second},
+		{2 * time.Second, 30 * time.Second, 3, 16 * time.Second},
+		{2 * time.Second, 30 * time.Second, 4, 30 * time.Second},
+		{2 * time.Second, 30 * time.Second, 100, 30 * time.Second},
+		{0, 30 * time.Second, 0, time.Second},
+		{100 * time.Millisecond, 500 * time.Millisecond, 10, 500 * time.Millisecond},
+		{time.Nanosecond, time.Nanosecond, 0, time.Nanosecond},
+	}
+	for _, test := range tests {
+		pollInterval, maxPollInterval = test.interval, test.max
+		// The upper half of the delay is jittered.
+		for i := 0; i < 20; i++ {
+			got := pollDelay(test.attempt)
+			if got < test.want/2 || got > test.want {
+				t.Errorf("pollDelay(%d) with --poll-interval %s and --max-poll-interval %s = %s, want %s to %s",
+					test.attempt, test.interval, test.max, got, test.want/2, test.want)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+// States --wait-until waits for.
+const (
+	waitUntilStopped = "stopped"
+	waitUntilRunning = "running"
+	waitUntilHealthy = "healthy"
+)
+
+var waitUntil string
+
+// validateWaitUntil exits if --wait-until has an invalid value.
+func validateWaitUntil() {
+	if waitUntil != waitUntilStopped && waitUntil != waitUntilRunning && waitUntil != waitUntilHealthy {
+		fmt.Printf("Invalid --wait-until %q, expected %s, %s or %s\n", waitUntil, waitUntilStopped, waitUntilRunning, waitUntilHealthy)
+		exit(1)
+	}
+}
+
+// checkHealthCheck exits if --wait-until healthy is used with a task
+// definition without health checks, whose tasks never become healthy.
+func checkHealthCheck(taskDefinition *ecstypes.TaskDefinition) {
+	if waitUntil != waitUntilHealthy {
+		return
+	}
+	for _, c := range taskDefinition.ContainerDefinitions {
+		if c.HealthCheck != nil {
+			return
+		}
+	}
+	fmt.Println("--wait-until healthy needs a task definition with a container health check")
+	exit(1)
+}
+
+// waitForStart waits until every task of a run is running, or healthy with
+// --wait-until healthy, prints how to reach them and exits with 0. A task
+// that stops instead is reported like any finished run.
+func waitForStart(ctx context.Context, cfg aws.Config, state *runState) {
+	waitCtx := ctx
+	if state.WaitTimeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, state.WaitTimeout)
+		defer cancel()
+	}
+	svc := ecs.NewFromConfig(cfg)
+	var tasks []*ecstypes.Task
+	for _, taskArn := range state.tasks() {
+		task, err := waitForTaskStart(waitCtx, svc, state.Cluster, taskArn)
+		if waitCtx.Err() != nil {
+			exitWithError("Got error waiting for the task:", fmt.Errorf("task %s did not become %s within --wait-timeout %s, it is left running", taskArn, waitUntil, state.WaitTimeout))
+		}
+		if err != nil {
+			exitWithError("Got error waiting for the task:", err)
+		}
+		if aws.ToString(task.LastStatus) != string(ecstypes.DesiredStatusRunning) {
+			monitorTask(ctx, cfg, state)
+		}
+		tasks = append(tasks, task)
+	}
+	for _, task := range tasks {
+		printTaskAddresses(ctx, cfg, task)
+	}
+	recordHistory(state, nil, "", "")
+	printRunResult(newRunResult(state))
+	removeState(state)
+	printStatus(true, fmt.Sprintf("task is %s", waitUntil))
+	exit(0)
+}
+
+// waitForTaskStart waits until the task is running, or healthy with
+// --wait-until healthy, or passed that state. It returns the last
+// description of the task.
+func waitForTaskStart(ctx context.Context, svc *ecs.Client, cluster string, taskArn string) (*ecstypes.Task, error) {
+	task, err := waitForTask(ctx, svc, cluster, taskArn, ecstypes.DesiredStatusRunning)
+	for attempt := 0; err == nil && waitUntil == waitUntilHealthy; attempt++ {
+		if aws.ToString(task.LastStatus) != string(ecstypes.DesiredStatusRunning) || task.HealthStatus == ecstypes.HealthStatusHealthy {
+			break
+		}
+		if verbose {
+			fmt.Printf("Task is %s, waiting for it to become healthy\n", task.HealthStatus)
+		}
+		select {
+		case <-ctx.Done():
+			return task, ctx.Err()
+		case <-time.After(pollDelay(attempt)):
+		}
+		task, err = waitForTask(ctx, svc, cluster, taskArn, ecstypes.DesiredStatusRunning)
+	}
+	return task, err
+}